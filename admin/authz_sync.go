@@ -0,0 +1,805 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+)
+
+const (
+	// ManagedAttribute is the attribute that marks keycloak resources as
+	// managed by `AuthzSync`. Only managed resources are deleted.
+	ManagedAttribute = "recloak-managed"
+
+	// PermissionPrefix is the prefix of the names of the permissions managed
+	// by `AuthzSync`, followed by the path of the resource.
+	PermissionPrefix = "recloak: "
+)
+
+// SyncAction is the kind of a change made by `AuthzSync` or `RolesReconciler`.
+type SyncAction string
+
+const (
+	SyncActionCreate SyncAction = "create"
+	SyncActionUpdate SyncAction = "update"
+	SyncActionDelete SyncAction = "delete"
+)
+
+// SyncObject is the kind of keycloak object changed by `AuthzSync` or
+// `RolesReconciler`.
+type SyncObject string
+
+const (
+	SyncObjectScope      SyncObject = "scope"
+	SyncObjectResource   SyncObject = "resource"
+	SyncObjectPermission SyncObject = "permission"
+	SyncObjectRole       SyncObject = "role"
+)
+
+// SyncChange is a change to the authorization settings or roles of a keycloak
+// client.
+type SyncChange struct {
+	Action SyncAction
+	Object SyncObject
+	Name   string
+
+	// A human-readable description of the new state, empty on deletion.
+	Details string
+}
+
+// String returns the change as a diff line, e.g. `+ scope read`.
+func (c SyncChange) String() string {
+	var sign string
+	switch c.Action {
+	case SyncActionCreate:
+		sign = "+"
+	case SyncActionUpdate:
+		sign = "~"
+	default:
+		sign = "-"
+	}
+
+	line := fmt.Sprintf("%s %s %s", sign, c.Object, c.Name)
+	if c.Details != "" {
+		line += " (" + c.Details + ")"
+	}
+
+	return line
+}
+
+// SyncPlan is a set of changes that brings the authorization settings or roles
+// of a keycloak client in line with an authorization configuration.
+type SyncPlan struct {
+	// The changes, in the order in which they are applied.
+	Changes []SyncChange
+
+	// Problems found while planning, which are not fixed by the changes.
+	Warnings []string
+
+	ops []syncOp
+}
+
+// IsEmpty checks whether the plan has no changes.
+func (p *SyncPlan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns the changes of the plan as a diff, one change per line,
+// followed by its warnings prefixed with `!`.
+func (p *SyncPlan) String() string {
+	var sb strings.Builder
+	for _, change := range p.Changes {
+		sb.WriteString(change.String())
+		sb.WriteByte('\n')
+	}
+
+	for _, warning := range p.Warnings {
+		sb.WriteString("! ")
+		sb.WriteString(warning)
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// AuthzSync is a type that exports the resources and policies of an
+// authorization configuration to the authorization settings of the keycloak
+// client, so that they show up in the keycloak admin console.
+//
+// Every resource with a policy or a keycloak permission is exported as a
+// permission named after its path. Resources with a keycloak permission are
+// exported as keycloak resources with their scopes, and other resources are
+// exported as keycloak resources named after their path.
+type AuthzSync struct {
+	client *recloak.ReCloak
+}
+
+// NewAuthzSync creates a new AuthzSync instance.
+func NewAuthzSync(client *recloak.ReCloak) *AuthzSync {
+	return &AuthzSync{client: client}
+}
+
+// Plan compares the given configuration with the authorization settings of the
+// client, and returns the changes needed to sync them without applying them.
+//
+// The admin token is taken from the context.
+func (s *AuthzSync) Plan(
+	ctx context.Context,
+	config *authz.AuthzConfig,
+) (*SyncPlan, error) {
+	engine, err := authz.NewEngine(config)
+	if err != nil {
+		return nil, err
+	}
+
+	token, repr, err := getTokenAndRepresentation(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.fetchState(ctx, token.Raw, *repr.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	desired, warnings := desiredSyncState(engine.ProtectedResources(), existing.policies)
+
+	plan := diffSyncState(desired, existing)
+	plan.Warnings = append(warnings, plan.Warnings...)
+
+	for _, warning := range plan.Warnings {
+		log.Warn().Msg(warning)
+	}
+
+	return plan, nil
+}
+
+// Apply applies the changes of the given plan.
+//
+// The admin token is taken from the context.
+func (s *AuthzSync) Apply(ctx context.Context, plan *SyncPlan) error {
+	token, repr, err := getTokenAndRepresentation(ctx, s.client)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range plan.ops {
+		log.Debug().Stringer("change", op.change).Msg("applying authorization change")
+
+		if err := s.apply(ctx, token.Raw, *repr.ID, op); err != nil {
+			return fmt.Errorf("could not apply `%s`: %w", op.change, err)
+		}
+	}
+
+	return nil
+}
+
+// Sync plans and applies the changes needed to sync the given configuration,
+// returning the applied plan.
+func (s *AuthzSync) Sync(
+	ctx context.Context,
+	config *authz.AuthzConfig,
+) (*SyncPlan, error) {
+	plan, err := s.Plan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, s.Apply(ctx, plan)
+}
+
+func (s *AuthzSync) apply(
+	ctx context.Context,
+	token string,
+	clientID string,
+	op syncOp,
+) error {
+	client := s.client.Client()
+	realm := s.client.Config().Realm
+
+	switch op.change.Object {
+	case SyncObjectScope:
+		if op.change.Action == SyncActionDelete {
+			return client.DeleteScope(ctx, token, realm, clientID, op.id)
+		}
+
+		_, err := client.CreateScope(ctx, token, realm, clientID, gocloak.ScopeRepresentation{
+			Name: gocloak.StringP(op.change.Name),
+		})
+
+		return err
+
+	case SyncObjectResource:
+		switch op.change.Action {
+		case SyncActionCreate:
+			_, err := client.CreateResource(ctx, token, realm, clientID, op.resource.representation())
+			return err
+
+		case SyncActionUpdate:
+			resource := op.resource.mergeInto(op.currentResource.repr)
+			resource.ID = gocloak.StringP(op.id)
+
+			return client.UpdateResource(ctx, token, realm, clientID, resource)
+
+		default:
+			return client.DeleteResource(ctx, token, realm, clientID, op.id)
+		}
+
+	default:
+		switch op.change.Action {
+		case SyncActionCreate:
+			_, err := client.CreatePermission(ctx, token, realm, clientID, op.permission.representation())
+			return err
+
+		case SyncActionUpdate:
+			permission := op.permission.representation()
+			permission.ID = gocloak.StringP(op.id)
+
+			return client.UpdatePermission(ctx, token, realm, clientID, permission)
+
+		default:
+			return client.DeletePermission(ctx, token, realm, clientID, op.id)
+		}
+	}
+}
+
+// fetchState fetches the current authorization settings of the client.
+func (s *AuthzSync) fetchState(
+	ctx context.Context,
+	token string,
+	clientID string,
+) (*syncState, error) {
+	client := s.client.Client()
+	realm := s.client.Config().Realm
+	state := newSyncState()
+
+	scopes, err := fetchAll(func(first, max int) ([]*gocloak.ScopeRepresentation, error) {
+		return client.GetScopes(ctx, token, realm, clientID, gocloak.GetScopeParams{
+			First: gocloak.IntP(first),
+			Max:   gocloak.IntP(max),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scope := range scopes {
+		state.scopes[gocloak.PString(scope.Name)] = gocloak.PString(scope.ID)
+	}
+
+	resources, err := fetchAll(func(first, max int) ([]*gocloak.ResourceRepresentation, error) {
+		return client.GetResources(ctx, token, realm, clientID, gocloak.GetResourceParams{
+			Deep:  gocloak.BoolP(true),
+			First: gocloak.IntP(first),
+			Max:   gocloak.IntP(max),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resource := range resources {
+		synced := resourceFromRepresentation(resource)
+		state.resources[synced.name] = synced
+	}
+
+	policies, err := fetchAll(func(first, max int) ([]*gocloak.PolicyRepresentation, error) {
+		return client.GetPolicies(ctx, token, realm, clientID, gocloak.GetPolicyParams{
+			Permission: gocloak.BoolP(false),
+			First:      gocloak.IntP(first),
+			Max:        gocloak.IntP(max),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		state.policies[gocloak.PString(policy.Name)] = struct{}{}
+	}
+
+	permissions, err := fetchAll(func(first, max int) ([]*gocloak.PermissionRepresentation, error) {
+		return client.GetPermissions(ctx, token, realm, clientID, gocloak.GetPermissionParams{
+			First: gocloak.IntP(first),
+			Max:   gocloak.IntP(max),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, permission := range permissions {
+		name := gocloak.PString(permission.Name)
+		if !strings.HasPrefix(name, PermissionPrefix) {
+			continue
+		}
+
+		synced, err := s.fetchPermission(ctx, token, clientID, permission)
+		if err != nil {
+			return nil, err
+		}
+
+		state.permissions[name] = synced
+	}
+
+	return state, nil
+}
+
+// fetchPermission fetches the resources, scopes and policies of a permission,
+// which are not included when listing permissions.
+func (s *AuthzSync) fetchPermission(
+	ctx context.Context,
+	token string,
+	clientID string,
+	permission *gocloak.PermissionRepresentation,
+) (*syncPermission, error) {
+	client := s.client.Client()
+	realm := s.client.Config().Realm
+	id := gocloak.PString(permission.ID)
+
+	synced := &syncPermission{
+		id:          id,
+		name:        gocloak.PString(permission.Name),
+		description: gocloak.PString(permission.Description),
+	}
+
+	resources, err := client.GetPermissionResources(ctx, token, realm, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resource := range resources {
+		synced.resources = append(synced.resources, gocloak.PString(resource.ResourceName))
+	}
+
+	scopes, err := client.GetPermissionScopes(ctx, token, realm, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scope := range scopes {
+		synced.scopes = append(synced.scopes, gocloak.PString(scope.ScopeName))
+	}
+
+	policies, err := client.GetAuthorizationPolicyAssociatedPolicies(ctx, token, realm, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		synced.policies = append(synced.policies, gocloak.PString(policy.Name))
+	}
+
+	synced.normalize()
+
+	return synced, nil
+}
+
+// syncState is the state of the authorization settings of a client.
+type syncState struct {
+	// scope IDs by name
+	scopes map[string]string
+
+	resources   map[string]*syncResource
+	permissions map[string]*syncPermission
+
+	// names of the policies that are not permissions
+	policies map[string]struct{}
+}
+
+func newSyncState() *syncState {
+	return &syncState{
+		scopes:      make(map[string]string),
+		resources:   make(map[string]*syncResource),
+		permissions: make(map[string]*syncPermission),
+		policies:    make(map[string]struct{}),
+	}
+}
+
+type syncResource struct {
+	id          string
+	name        string
+	displayName string
+	scopes      []string
+	managed     bool
+
+	// the fetched representation of an existing resource
+	repr *gocloak.ResourceRepresentation
+}
+
+type syncPermission struct {
+	id          string
+	name        string
+	description string
+	resources   []string
+	scopes      []string
+	policies    []string
+}
+
+// syncOp is a change along with the data needed to apply it.
+type syncOp struct {
+	change     SyncChange
+	id         string
+	permission *syncPermission
+
+	// the declared and existing states of a resource
+	resource, currentResource *syncResource
+
+	// the declared and existing states of a role
+	role, currentRole *syncRole
+}
+
+func resourceFromRepresentation(resource *gocloak.ResourceRepresentation) *syncResource {
+	synced := &syncResource{
+		id:          gocloak.PString(resource.ID),
+		name:        gocloak.PString(resource.Name),
+		displayName: gocloak.PString(resource.DisplayName),
+		repr:        resource,
+	}
+
+	for _, scopes := range []*[]gocloak.ScopeRepresentation{resource.Scopes, resource.ResourceScopes} {
+		if scopes == nil {
+			continue
+		}
+
+		for _, scope := range *scopes {
+			synced.scopes = append(synced.scopes, gocloak.PString(scope.Name))
+		}
+	}
+
+	if resource.Attributes != nil {
+		values := (*resource.Attributes)[ManagedAttribute]
+		synced.managed = slices.Contains(values, "true")
+	}
+
+	synced.normalize()
+
+	return synced
+}
+
+func (r *syncResource) normalize() {
+	r.scopes = sortedUnique(r.scopes)
+}
+
+func (r *syncResource) equal(other *syncResource) bool {
+	return r.displayName == other.displayName &&
+		r.managed == other.managed &&
+		slices.Equal(r.scopes, other.scopes)
+}
+
+func (r *syncResource) details() string {
+	var details []string
+	if r.displayName != "" {
+		details = append(details, "display name: "+r.displayName)
+	}
+	if len(r.scopes) > 0 {
+		details = append(details, "scopes: "+strings.Join(r.scopes, ", "))
+	}
+
+	return strings.Join(details, ", ")
+}
+
+func (r *syncResource) representation() gocloak.ResourceRepresentation {
+	scopes := make([]gocloak.ScopeRepresentation, len(r.scopes))
+	for i, scope := range r.scopes {
+		scopes[i] = gocloak.ScopeRepresentation{Name: gocloak.StringP(scope)}
+	}
+
+	resource := gocloak.ResourceRepresentation{
+		Name:       gocloak.StringP(r.name),
+		Scopes:     &scopes,
+		Attributes: &map[string][]string{ManagedAttribute: {"true"}},
+	}
+
+	if r.displayName != "" {
+		resource.DisplayName = gocloak.StringP(r.displayName)
+	}
+
+	return resource
+}
+
+// mergeInto returns the given representation of the existing resource with the
+// state of the resource applied, so that the attributes, URIs, owner and type
+// of the existing resource are kept on update.
+func (r *syncResource) mergeInto(
+	current *gocloak.ResourceRepresentation,
+) gocloak.ResourceRepresentation {
+	desired := r.representation()
+	if current == nil {
+		return desired
+	}
+
+	resource := *current
+	resource.Name = desired.Name
+	resource.DisplayName = gocloak.StringP(r.displayName)
+	resource.Scopes = desired.Scopes
+	resource.ResourceScopes = nil
+
+	attributes := make(map[string][]string)
+	if current.Attributes != nil {
+		maps.Copy(attributes, *current.Attributes)
+	}
+	attributes[ManagedAttribute] = []string{"true"}
+	resource.Attributes = &attributes
+
+	return resource
+}
+
+func (p *syncPermission) normalize() {
+	p.resources = sortedUnique(p.resources)
+	p.scopes = sortedUnique(p.scopes)
+	p.policies = sortedUnique(p.policies)
+}
+
+func (p *syncPermission) equal(other *syncPermission) bool {
+	return p.description == other.description &&
+		slices.Equal(p.resources, other.resources) &&
+		slices.Equal(p.scopes, other.scopes) &&
+		slices.Equal(p.policies, other.policies)
+}
+
+func (p *syncPermission) details() string {
+	details := "resources: " + strings.Join(p.resources, ", ")
+	if len(p.scopes) > 0 {
+		details += ", scopes: " + strings.Join(p.scopes, ", ")
+	}
+	if len(p.policies) > 0 {
+		details += ", policies: " + strings.Join(p.policies, ", ")
+	}
+
+	return details
+}
+
+func (p *syncPermission) representation() gocloak.PermissionRepresentation {
+	permission := gocloak.PermissionRepresentation{
+		Name:             gocloak.StringP(p.name),
+		Description:      gocloak.StringP(p.description),
+		Type:             gocloak.StringP("resource"),
+		DecisionStrategy: gocloak.UNANIMOUS,
+		Resources:        &p.resources,
+		Policies:         &p.policies,
+	}
+
+	if len(p.scopes) > 0 {
+		permission.Type = gocloak.StringP("scope")
+		permission.Scopes = &p.scopes
+	}
+
+	return permission
+}
+
+// desiredSyncState returns the state that the given resources map to. Only
+// the given existing policies are associated with permissions, and a warning
+// is returned for every policy of a resource that does not exist or has no
+// name, and for every permission left without policies.
+func desiredSyncState(
+	resources []authz.ProtectedResource,
+	policies map[string]struct{},
+) (*syncState, []string) {
+	state := newSyncState()
+
+	var warnings []string
+
+	for _, resource := range resources {
+		name, scopes := resource.Path, []string(nil)
+		if resource.Keycloak != nil {
+			name, scopes = resource.Keycloak.Resource, resource.Keycloak.Scopes
+		}
+
+		synced, ok := state.resources[name]
+		if !ok {
+			synced = &syncResource{name: name, managed: true}
+			state.resources[name] = synced
+		}
+
+		if synced.displayName == "" {
+			synced.displayName = resource.DisplayName
+		}
+
+		synced.scopes = append(synced.scopes, scopes...)
+		synced.normalize()
+
+		for _, scope := range scopes {
+			state.scopes[scope] = ""
+		}
+
+		permission := &syncPermission{
+			name:        PermissionPrefix + resource.Path,
+			description: resource.Expression,
+			resources:   []string{name},
+			scopes:      slices.Clone(scopes),
+		}
+
+		for _, clause := range resource.Clauses {
+			if clause.Policy == "" {
+				// unnamed in-place policies have no keycloak counterpart
+				warnings = append(warnings, fmt.Sprintf(
+					"unnamed policy of resource `%s` is left out of permission `%s`",
+					clause.Resource,
+					permission.name,
+				))
+
+				continue
+			}
+
+			if _, ok := policies[clause.Policy]; ok {
+				permission.policies = append(permission.policies, clause.Policy)
+			} else {
+				warnings = append(warnings, fmt.Sprintf(
+					"policy `%s` of resource `%s` does not exist in keycloak",
+					clause.Policy,
+					resource.Path,
+				))
+			}
+		}
+
+		permission.normalize()
+		state.permissions[permission.name] = permission
+
+		if len(permission.policies) == 0 {
+			warnings = append(warnings, fmt.Sprintf(
+				"permission `%s` has no policies, so keycloak denies everyone",
+				permission.name,
+			))
+		}
+	}
+
+	return state, warnings
+}
+
+// diffSyncState returns the plan that changes the existing state into the
+// desired one. Existing resources are deleted only if they are managed, and
+// scopes only if they are used by deleted or updated managed resources only.
+func diffSyncState(desired, existing *syncState) *SyncPlan {
+	plan := &SyncPlan{}
+	add := func(op syncOp) {
+		plan.Changes = append(plan.Changes, op.change)
+		plan.ops = append(plan.ops, op)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(desired.scopes)) {
+		if _, ok := existing.scopes[name]; !ok {
+			add(syncOp{change: SyncChange{
+				Action: SyncActionCreate,
+				Object: SyncObjectScope,
+				Name:   name,
+			}})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(desired.resources)) {
+		resource := desired.resources[name]
+		current, ok := existing.resources[name]
+
+		switch {
+		case !ok:
+			add(syncOp{
+				change: SyncChange{
+					Action:  SyncActionCreate,
+					Object:  SyncObjectResource,
+					Name:    name,
+					Details: resource.details(),
+				},
+				resource: resource,
+			})
+
+		case !resource.equal(current):
+			add(syncOp{
+				change: SyncChange{
+					Action:  SyncActionUpdate,
+					Object:  SyncObjectResource,
+					Name:    name,
+					Details: resource.details(),
+				},
+				id:              current.id,
+				resource:        resource,
+				currentResource: current,
+			})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(desired.permissions)) {
+		permission := desired.permissions[name]
+		current, ok := existing.permissions[name]
+
+		switch {
+		case !ok:
+			add(syncOp{
+				change: SyncChange{
+					Action:  SyncActionCreate,
+					Object:  SyncObjectPermission,
+					Name:    name,
+					Details: permission.details(),
+				},
+				permission: permission,
+			})
+
+		case !permission.equal(current):
+			add(syncOp{
+				change: SyncChange{
+					Action:  SyncActionUpdate,
+					Object:  SyncObjectPermission,
+					Name:    name,
+					Details: permission.details(),
+				},
+				id:         current.id,
+				permission: permission,
+			})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(existing.permissions)) {
+		if _, ok := desired.permissions[name]; !ok {
+			add(syncOp{
+				change: SyncChange{
+					Action: SyncActionDelete,
+					Object: SyncObjectPermission,
+					Name:   name,
+				},
+				id: existing.permissions[name].id,
+			})
+		}
+	}
+
+	// scopes that are still used by resources that are kept as they are
+	usedScopes := make(map[string]struct{})
+	managedScopes := make(map[string]struct{})
+
+	for _, name := range slices.Sorted(maps.Keys(existing.resources)) {
+		resource := existing.resources[name]
+		_, isDesired := desired.resources[name]
+
+		for _, scope := range resource.scopes {
+			if resource.managed {
+				managedScopes[scope] = struct{}{}
+			} else if !isDesired {
+				usedScopes[scope] = struct{}{}
+			}
+		}
+
+		if resource.managed && !isDesired {
+			add(syncOp{
+				change: SyncChange{
+					Action: SyncActionDelete,
+					Object: SyncObjectResource,
+					Name:   name,
+				},
+				id: resource.id,
+			})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(managedScopes)) {
+		_, isDesired := desired.scopes[name]
+		_, isUsed := usedScopes[name]
+		id, exists := existing.scopes[name]
+
+		if !isDesired && !isUsed && exists {
+			add(syncOp{
+				change: SyncChange{
+					Action: SyncActionDelete,
+					Object: SyncObjectScope,
+					Name:   name,
+				},
+				id: id,
+			})
+		}
+	}
+
+	return plan
+}
+
+func sortedUnique(values []string) []string {
+	values = slices.Clone(values)
+	slices.Sort(values)
+
+	return slices.Compact(values)
+}
@@ -0,0 +1,264 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak/authz"
+)
+
+func TestDiffSyncState(t *testing.T) {
+	config := authz.AuthzConfig{
+		PathSeparator: ".",
+		Policies: []authz.Policy{
+			{Name: "is_user", Expression: "true"},
+		},
+		Resources: []authz.Resource{
+			{
+				Name:     "orders",
+				Keycloak: &authz.KeycloakPermission{Resource: "orders"},
+				Policy:   &authz.PolicySpec{Ref: "is_user"},
+				Children: []authz.Resource{
+					{
+						Name:     "get",
+						Keycloak: &authz.KeycloakPermission{Scopes: []string{"read"}},
+					},
+				},
+			},
+			{
+				Name:        "health",
+				DisplayName: "Health",
+				Policy:      &authz.PolicySpec{InPlace: &authz.Policy{Expression: "true"}},
+			},
+		},
+	}
+
+	engine, err := authz.NewEngine(&config)
+	require.NoError(t, err)
+
+	existing := newSyncState()
+	existing.policies["is_user"] = struct{}{}
+	existing.scopes["read"] = "scope-read"
+	existing.scopes["write"] = "scope-write"
+	existing.scopes["manual"] = "scope-manual"
+	existing.resources["orders"] = &syncResource{
+		id:      "resource-orders",
+		name:    "orders",
+		scopes:  []string{"read", "write"},
+		managed: true,
+	}
+	existing.resources["legacy"] = &syncResource{
+		id:      "resource-legacy",
+		name:    "legacy",
+		managed: true,
+	}
+	existing.resources["manual"] = &syncResource{
+		id:     "resource-manual",
+		name:   "manual",
+		scopes: []string{"manual"},
+	}
+	existing.permissions["recloak: orders"] = &syncPermission{
+		id:          "permission-orders",
+		name:        "recloak: orders",
+		description: "(true)",
+		resources:   []string{"orders"},
+		policies:    []string{"is_user"},
+	}
+	existing.permissions["recloak: legacy"] = &syncPermission{
+		id:        "permission-legacy",
+		name:      "recloak: legacy",
+		resources: []string{"legacy"},
+	}
+
+	desired, warnings := desiredSyncState(engine.ProtectedResources(), existing.policies)
+	require.Equal(t, []string{
+		"unnamed policy of resource `health` is left out of permission `recloak: health`",
+		"permission `recloak: health` has no policies, so keycloak denies everyone",
+	}, warnings)
+
+	plan := diffSyncState(desired, existing)
+
+	require.Equal(t, ""+
+		"+ resource health (display name: Health)\n"+
+		"~ resource orders (scopes: read)\n"+
+		"+ permission recloak: health (resources: health)\n"+
+		"+ permission recloak: orders.get (resources: orders, scopes: read, policies: is_user)\n"+
+		"- permission recloak: legacy\n"+
+		"- resource legacy\n"+
+		"- scope write\n",
+		plan.String(),
+	)
+
+	for _, op := range plan.ops {
+		if op.change.Action != SyncActionCreate {
+			require.NotEmpty(t, op.id, op.change.String())
+		}
+	}
+
+	t.Run("no changes", func(t *testing.T) {
+		plan := diffSyncState(desired, desired)
+		require.True(t, plan.IsEmpty())
+	})
+}
+
+func TestFetchStatePagesThroughListings(t *testing.T) {
+	// serve serves the objects of a listing a page at a time, as keycloak
+	// does, which returns 100 objects at most if no page is requested
+	serve := func(count int, object func(i int) any) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			first, max := 0, 100
+			if value := r.URL.Query().Get("first"); value != "" {
+				first, _ = strconv.Atoi(value)
+			}
+			if value := r.URL.Query().Get("max"); value != "" {
+				max, _ = strconv.Atoi(value)
+			}
+
+			page := []any{}
+			for i := first; i < count && i < first+max; i++ {
+				page = append(page, object(i))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(page)
+		}
+	}
+
+	const prefix = "/admin/realms/test/clients/id-client/authz/resource-server"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/scope", serve(150, func(i int) any {
+		return gocloak.ScopeRepresentation{
+			ID:   gocloak.StringP(fmt.Sprintf("id-scope-%d", i)),
+			Name: gocloak.StringP(fmt.Sprintf("scope-%d", i)),
+		}
+	}))
+	mux.HandleFunc("GET "+prefix+"/resource", serve(250, func(i int) any {
+		return gocloak.ResourceRepresentation{
+			ID:   gocloak.StringP(fmt.Sprintf("id-resource-%d", i)),
+			Name: gocloak.StringP(fmt.Sprintf("resource-%d", i)),
+		}
+	}))
+	mux.HandleFunc("GET "+prefix+"/policy", serve(200, func(i int) any {
+		return gocloak.PolicyRepresentation{
+			ID:   gocloak.StringP(fmt.Sprintf("id-policy-%d", i)),
+			Name: gocloak.StringP(fmt.Sprintf("policy-%d", i)),
+		}
+	}))
+	mux.HandleFunc("GET "+prefix+"/permission", serve(120, func(i int) any {
+		return gocloak.PermissionRepresentation{
+			ID:   gocloak.StringP(fmt.Sprintf("id-permission-%d", i)),
+			Name: gocloak.StringP(fmt.Sprintf("%spermission-%d", PermissionPrefix, i)),
+		}
+	}))
+	mux.HandleFunc("GET "+prefix+"/permission/{id}/{kind}", serve(0, nil))
+	mux.HandleFunc("GET "+prefix+"/policy/{id}/associatedPolicies", serve(0, nil))
+
+	client, ctx := newTestAdminClient(t, mux)
+
+	state, err := NewAuthzSync(client).fetchState(ctx, "admin", "id-client")
+	require.NoError(t, err)
+
+	require.Len(t, state.scopes, 150)
+	require.Len(t, state.resources, 250)
+	require.Len(t, state.policies, 200)
+	require.Len(t, state.permissions, 120)
+
+	require.Equal(t, "id-scope-149", state.scopes["scope-149"])
+	require.Equal(t, "id-resource-249", state.resources["resource-249"].id)
+	require.Contains(t, state.policies, "policy-199")
+	require.Contains(t, state.permissions, PermissionPrefix+"permission-119")
+}
+
+func TestDesiredSyncStateWarnsOfMissingPolicies(t *testing.T) {
+	config := authz.AuthzConfig{
+		PathSeparator: ".",
+		Policies: []authz.Policy{
+			{Name: "is_user", Expression: "true"},
+			{Name: "is_admin", Expression: "true"},
+		},
+		Resources: []authz.Resource{
+			{
+				Name:   "orders",
+				Policy: &authz.PolicySpec{Ref: "is_user"},
+				Children: []authz.Resource{
+					{
+						Name:   "delete",
+						Policy: &authz.PolicySpec{Ref: "is_admin"},
+					},
+				},
+			},
+			{
+				Name:   "admin",
+				Policy: &authz.PolicySpec{Ref: "is_admin"},
+			},
+			{
+				Name: "reports",
+				Policy: &authz.PolicySpec{
+					InPlace: &authz.Policy{Expression: "true"},
+				},
+			},
+		},
+	}
+
+	engine, err := authz.NewEngine(&config)
+	require.NoError(t, err)
+
+	desired, warnings := desiredSyncState(
+		engine.ProtectedResources(),
+		map[string]struct{}{"is_user": {}},
+	)
+
+	require.Equal(t, []string{"is_user"}, desired.permissions["recloak: orders.delete"].policies)
+	require.Equal(t, []string{
+		"policy `is_admin` of resource `admin` does not exist in keycloak",
+		"permission `recloak: admin` has no policies, so keycloak denies everyone",
+		"policy `is_admin` of resource `orders.delete` does not exist in keycloak",
+		"unnamed policy of resource `reports` is left out of permission `recloak: reports`",
+		"permission `recloak: reports` has no policies, so keycloak denies everyone",
+	}, warnings)
+}
+
+func TestSyncResourceMergeInto(t *testing.T) {
+	current := &gocloak.ResourceRepresentation{
+		ID:          gocloak.StringP("resource-orders"),
+		Name:        gocloak.StringP("orders"),
+		DisplayName: gocloak.StringP("Old"),
+		Type:        gocloak.StringP("urn:orders"),
+		URIs:        &[]string{"/orders/*"},
+		Owner:       &gocloak.ResourceOwnerRepresentation{ID: gocloak.StringP("owner")},
+		Attributes:  &map[string][]string{"team": {"billing"}},
+		ResourceScopes: &[]gocloak.ScopeRepresentation{
+			{Name: gocloak.StringP("write")},
+		},
+	}
+
+	resource := &syncResource{
+		name:        "orders",
+		displayName: "Orders",
+		scopes:      []string{"read"},
+		managed:     true,
+	}
+
+	merged := resource.mergeInto(current)
+
+	require.Equal(t, "Orders", gocloak.PString(merged.DisplayName))
+	require.Equal(t, "urn:orders", gocloak.PString(merged.Type))
+	require.Equal(t, []string{"/orders/*"}, *merged.URIs)
+	require.Equal(t, "owner", gocloak.PString(merged.Owner.ID))
+	require.Equal(t, map[string][]string{
+		"team":           {"billing"},
+		ManagedAttribute: {"true"},
+	}, *merged.Attributes)
+	require.Equal(t, []gocloak.ScopeRepresentation{{Name: gocloak.StringP("read")}}, *merged.Scopes)
+	require.Nil(t, merged.ResourceScopes)
+
+	// the fetched attributes are not modified
+	require.Equal(t, map[string][]string{"team": {"billing"}}, *current.Attributes)
+}
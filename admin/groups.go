@@ -0,0 +1,409 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+)
+
+// ErrInvalidGroupPath is returned when a group path is empty or has empty
+// segments.
+var ErrInvalidGroupPath = errors.New("invalid group path")
+
+// Group is a re-export of `gocloak.Group` for convenience.
+type Group = gocloak.Group
+
+// User is a re-export of `gocloak.User` for convenience.
+type User = gocloak.User
+
+// GroupsManager is a type that provides group management capabilities. Groups
+// are identified by their paths, e.g. `/tenants/acme/admins`.
+type GroupsManager struct {
+	client      *recloak.ReCloak
+	roles       *ClientRolesManager
+	groupsCache *objectCache[Group]
+}
+
+// NewGroupsManager creates a new GroupsManager instance.
+func NewGroupsManager(client *recloak.ReCloak) *GroupsManager {
+	return &GroupsManager{
+		client:      client,
+		roles:       NewClientRolesManager(client),
+		groupsCache: newObjectCache[Group](),
+	}
+}
+
+// GetGroupByPath returns a group by its path.
+func (m *GroupsManager) GetGroupByPath(
+	ctx context.Context,
+	groupPath string,
+) (*Group, error) {
+	segments, err := splitGroupPath(groupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.getGroup(ctx, token.Raw, joinGroupPath(segments))
+}
+
+// CreateGroup creates a group by its path, along with any missing parent
+// groups, and returns it. Existing groups are returned as is.
+func (m *GroupsManager) CreateGroup(
+	ctx context.Context,
+	groupPath string,
+) (*Group, error) {
+	segments, err := splitGroupPath(groupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *Group
+	for i, name := range segments {
+		path := joinGroupPath(segments[:i+1])
+
+		group, err := m.getGroup(ctx, token.Raw, path)
+		if err == nil {
+			parent = group
+			continue
+		}
+
+		if !isNotFound(err) {
+			return nil, err
+		}
+
+		if parent, err = m.createGroup(ctx, token.Raw, parent, name, path); err != nil {
+			// the cached parent group may have been deleted
+			m.dropIfNotFound(err, joinGroupPath(segments[:i]))
+			return nil, err
+		}
+	}
+
+	return parent, nil
+}
+
+// AddUserToGroup adds a user to a group.
+func (m *GroupsManager) AddUserToGroup(
+	ctx context.Context,
+	userID string,
+	groupPath string,
+) error {
+	log.Debug().
+		Str("user_id", userID).
+		Str("group", groupPath).
+		Msg("adding user to group")
+
+	token, group, err := m.getTokenAndGroup(ctx, groupPath)
+	if err != nil {
+		return err
+	}
+
+	err = m.client.Client().AddUserToGroup(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		userID,
+		*group.ID,
+	)
+	m.dropIfNotFound(err, groupPath)
+
+	return err
+}
+
+// RemoveUserFromGroup removes a user from a group.
+func (m *GroupsManager) RemoveUserFromGroup(
+	ctx context.Context,
+	userID string,
+	groupPath string,
+) error {
+	log.Debug().
+		Str("user_id", userID).
+		Str("group", groupPath).
+		Msg("removing user from group")
+
+	token, group, err := m.getTokenAndGroup(ctx, groupPath)
+	if err != nil {
+		return err
+	}
+
+	err = m.client.Client().DeleteUserFromGroup(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		userID,
+		*group.ID,
+	)
+	m.dropIfNotFound(err, groupPath)
+
+	return err
+}
+
+// GetGroupMembers returns a page of the direct members of a group, starting
+// at the given offset and holding at most `max` users.
+func (m *GroupsManager) GetGroupMembers(
+	ctx context.Context,
+	groupPath string,
+	first int,
+	max int,
+) ([]*User, error) {
+	token, group, err := m.getTokenAndGroup(ctx, groupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := m.client.Client().GetGroupMembers(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		*group.ID,
+		gocloak.GetGroupsParams{
+			First: gocloak.IntP(first),
+			Max:   gocloak.IntP(max),
+		},
+	)
+	m.dropIfNotFound(err, groupPath)
+
+	return members, err
+}
+
+// GetGroupRoles returns a list of client roles assigned to a group.
+func (m *GroupsManager) GetGroupRoles(
+	ctx context.Context,
+	groupPath string,
+) ([]*Role, error) {
+	token, group, err := m.getTokenAndGroup(ctx, groupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, repr, err := getTokenAndRepresentation(ctx, m.client)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := m.client.Client().GetClientRolesByGroupID(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		*repr.ID,
+		*group.ID,
+	)
+	m.dropIfNotFound(err, groupPath)
+
+	return roles, err
+}
+
+// AddRolesToGroup assigns client roles to a group.
+func (m *GroupsManager) AddRolesToGroup(
+	ctx context.Context,
+	groupPath string,
+	roleNames ...string,
+) error {
+	log.Debug().
+		Str("group", groupPath).
+		Strs("roles", roleNames).
+		Msg("adding roles to group")
+
+	token, group, err := m.getTokenAndGroup(ctx, groupPath)
+	if err != nil {
+		return err
+	}
+
+	_, repr, err := getTokenAndRepresentation(ctx, m.client)
+	if err != nil {
+		return err
+	}
+
+	roles, err := m.roles.GetRolesByName(ctx, roleNames...)
+	if err != nil {
+		return err
+	}
+
+	err = m.client.Client().AddClientRolesToGroup(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		*repr.ID,
+		*group.ID,
+		roles.owned(),
+	)
+	m.dropIfNotFound(err, groupPath)
+	m.roles.rolesCache.dropIfNotFound(err, roleNames...)
+
+	return err
+}
+
+// RemoveRolesFromGroup removes client roles from a group.
+func (m *GroupsManager) RemoveRolesFromGroup(
+	ctx context.Context,
+	groupPath string,
+	roleNames ...string,
+) error {
+	log.Debug().
+		Str("group", groupPath).
+		Strs("roles", roleNames).
+		Msg("removing roles from group")
+
+	token, group, err := m.getTokenAndGroup(ctx, groupPath)
+	if err != nil {
+		return err
+	}
+
+	_, repr, err := getTokenAndRepresentation(ctx, m.client)
+	if err != nil {
+		return err
+	}
+
+	roles, err := m.roles.GetRolesByName(ctx, roleNames...)
+	if err != nil {
+		return err
+	}
+
+	err = m.client.Client().DeleteClientRoleFromGroup(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		*repr.ID,
+		*group.ID,
+		roles.owned(),
+	)
+	m.dropIfNotFound(err, groupPath)
+	m.roles.rolesCache.dropIfNotFound(err, roleNames...)
+
+	return err
+}
+
+// getTokenAndGroup returns the token of the caller from the context, used to
+// call the admin API, along with the group of the given path.
+func (m *GroupsManager) getTokenAndGroup(
+	ctx context.Context,
+	groupPath string,
+) (recloak.Token, *Group, error) {
+	segments, err := splitGroupPath(groupPath)
+	if err != nil {
+		return recloak.Token{}, nil, err
+	}
+
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return recloak.Token{}, nil, err
+	}
+
+	group, err := m.getGroup(ctx, token.Raw, joinGroupPath(segments))
+	if err != nil {
+		return recloak.Token{}, nil, err
+	}
+
+	return token, group, nil
+}
+
+// dropIfNotFound drops the group of the given path and its subgroups from the
+// cache if the given error is a not found keycloak error, as the group may have
+// been deleted.
+func (m *GroupsManager) dropIfNotFound(err error, groupPath string) {
+	if !isNotFound(err) {
+		return
+	}
+
+	if segments, err := splitGroupPath(groupPath); err == nil {
+		m.groupsCache.dropTree(joinGroupPath(segments))
+	}
+}
+
+func (m *GroupsManager) getGroup(
+	ctx context.Context,
+	token string,
+	groupPath string,
+) (*Group, error) {
+	if group, ok := m.groupsCache.get(groupPath); ok {
+		return group, nil
+	}
+
+	group, err := m.client.Client().GetGroupByPath(
+		ctx,
+		token,
+		m.client.Config().Realm,
+		strings.TrimPrefix(groupPath, "/"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.groupsCache.put(groupPath, group)
+
+	return group, nil
+}
+
+func (m *GroupsManager) createGroup(
+	ctx context.Context,
+	token string,
+	parent *Group,
+	name string,
+	groupPath string,
+) (*Group, error) {
+	log.Debug().Str("group", groupPath).Msg("creating group")
+
+	group := Group{
+		Name: gocloak.StringP(name),
+		Path: gocloak.StringP(groupPath),
+	}
+
+	var id string
+	var err error
+	if parent == nil {
+		id, err = m.client.Client().CreateGroup(ctx, token, m.client.Config().Realm, group)
+	} else {
+		id, err = m.client.Client().CreateChildGroup(
+			ctx,
+			token,
+			m.client.Config().Realm,
+			*parent.ID,
+			group,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	group.ID = gocloak.StringP(id)
+	m.groupsCache.put(groupPath, &group)
+
+	return &group, nil
+}
+
+// splitGroupPath splits a group path into the names of the groups along it.
+func splitGroupPath(groupPath string) ([]string, error) {
+	trimmed := strings.Trim(groupPath, "/")
+	if trimmed == "" {
+		return nil, fmt.Errorf("%w: `%s`", ErrInvalidGroupPath, groupPath)
+	}
+
+	segments := strings.Split(trimmed, "/")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("%w: `%s`", ErrInvalidGroupPath, groupPath)
+		}
+	}
+
+	return segments, nil
+}
+
+// joinGroupPath joins the names of groups into a canonical group path.
+func joinGroupPath(segments []string) string {
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitGroupPath(t *testing.T) {
+	segments, err := splitGroupPath("/tenants/acme/admins")
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenants", "acme", "admins"}, segments)
+	require.Equal(t, "/tenants/acme/admins", joinGroupPath(segments))
+
+	segments, err = splitGroupPath("tenants/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenants"}, segments)
+
+	for _, path := range []string{"", "/", "/tenants//admins"} {
+		_, err := splitGroupPath(path)
+		require.ErrorIs(t, err, ErrInvalidGroupPath, path)
+	}
+}
+
+func TestGroupsManager(t *testing.T) {
+	groups := map[string]string{"tenants": "id-tenants"}
+	var created []string
+
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	createGroup := func(w http.ResponseWriter, r *http.Request, parent string) {
+		var group Group
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&group))
+
+		path := *group.Name
+		if parent != "" {
+			path = parent + "/" + path
+		}
+
+		groups[path] = "id-" + *group.Name
+		created = append(created, path)
+
+		w.Header().Set("Location", "/groups/id-"+*group.Name)
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/realms/test/group-by-path/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := groups[r.PathValue("path")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, Group{ID: gocloak.StringP(id), Path: gocloak.StringP("/" + r.PathValue("path"))})
+	})
+	mux.HandleFunc("POST /admin/realms/test/groups", func(w http.ResponseWriter, r *http.Request) {
+		createGroup(w, r, "")
+	})
+	mux.HandleFunc("POST /admin/realms/test/groups/{id}/children", func(w http.ResponseWriter, r *http.Request) {
+		for path, id := range groups {
+			if id == r.PathValue("id") {
+				createGroup(w, r, path)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /admin/realms/test/groups/{id}/members", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "id-admins", r.PathValue("id"))
+		require.Equal(t, "10", r.URL.Query().Get("first"))
+		require.Equal(t, "5", r.URL.Query().Get("max"))
+
+		writeJSON(w, []User{{Username: gocloak.StringP("alice")}})
+	})
+	mux.HandleFunc("PUT /admin/realms/test/users/{user}/groups/{id}", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "user", r.PathValue("user"))
+
+		for _, id := range groups {
+			if id == r.PathValue("id") {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	client, ctx := newTestAdminClient(t, mux)
+	manager := NewGroupsManager(client)
+
+	group, err := manager.CreateGroup(ctx, "/tenants/acme/admins")
+	require.NoError(t, err)
+	require.Equal(t, "id-admins", *group.ID)
+	require.Equal(t, "/tenants/acme/admins", *group.Path)
+	require.Equal(t, []string{"tenants/acme", "tenants/acme/admins"}, created)
+
+	group, err = manager.CreateGroup(ctx, "tenants/acme/admins/")
+	require.NoError(t, err)
+	require.Equal(t, "id-admins", *group.ID)
+	require.Len(t, created, 2)
+
+	require.NoError(t, manager.AddUserToGroup(ctx, "user", "/tenants/acme/admins"))
+
+	members, err := manager.GetGroupMembers(ctx, "/tenants/acme/admins", 10, 5)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	require.Equal(t, "alice", *members[0].Username)
+
+	_, err = manager.GetGroupByPath(ctx, "/tenants/other")
+	require.True(t, isNotFound(err))
+
+	// groups deleted in keycloak are dropped from the cache once not found
+	delete(groups, "tenants/acme")
+	delete(groups, "tenants/acme/admins")
+
+	err = manager.AddUserToGroup(ctx, "user", "/tenants/acme")
+	require.True(t, isNotFound(err))
+
+	_, err = manager.GetGroupByPath(ctx, "/tenants/acme/admins")
+	require.True(t, isNotFound(err))
+
+	_, err = manager.GetGroupByPath(ctx, "/tenants")
+	require.NoError(t, err)
+}
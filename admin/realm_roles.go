@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+)
+
+// RealmRolesManager is a type that provides realm role management
+// capabilities.
+type RealmRolesManager struct {
+	client     *recloak.ReCloak
+	rolesCache *objectCache[Role]
+}
+
+// NewRealmRolesManager creates a new RealmRolesManager instance.
+func NewRealmRolesManager(client *recloak.ReCloak) *RealmRolesManager {
+	return &RealmRolesManager{
+		client:     client,
+		rolesCache: newObjectCache[Role](),
+	}
+}
+
+// GetRolesByName returns a list of realm roles by their names.
+func (m *RealmRolesManager) GetRolesByName(
+	ctx context.Context,
+	roleNames ...string,
+) (Roles, error) {
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]*Role, len(roleNames))
+	for i, roleName := range roleNames {
+		if role, ok := m.rolesCache.get(roleName); ok {
+			roles[i] = role
+			continue
+		}
+
+		role, err := m.client.Client().GetRealmRole(
+			ctx,
+			token.Raw,
+			m.client.Config().Realm,
+			roleName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		m.rolesCache.put(roleName, role)
+		roles[i] = role
+	}
+
+	return roles, nil
+}
+
+// GetUserRoles returns a list of realm roles by user ID.
+func (m *RealmRolesManager) GetUserRoles(
+	ctx context.Context,
+	userID string,
+) ([]*Role, error) {
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := m.client.Client().GetRealmRolesByUserID(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		m.rolesCache.put(*role.Name, role)
+	}
+
+	return roles, nil
+}
+
+// GetUserEffectiveRoles returns a list of realm roles by user ID, including
+// the ones granted through composite roles and groups.
+func (m *RealmRolesManager) GetUserEffectiveRoles(
+	ctx context.Context,
+	userID string,
+) ([]*Role, error) {
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.client.Client().GetCompositeRealmRolesByUserID(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		userID,
+	)
+}
+
+// AddRolesToUser adds realm roles to a user.
+func (m *RealmRolesManager) AddRolesToUser(
+	ctx context.Context,
+	userID string,
+	roleNames ...string,
+) error {
+	log.Debug().
+		Str("user_id", userID).
+		Strs("roles", roleNames).
+		Msg("adding realm roles to user")
+
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	roles, err := m.GetRolesByName(ctx, roleNames...)
+	if err != nil {
+		return err
+	}
+
+	err = m.client.Client().AddRealmRoleToUser(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		userID,
+		roles.owned(),
+	)
+	m.rolesCache.dropIfNotFound(err, roleNames...)
+
+	return err
+}
+
+// RemoveRolesFromUser removes realm roles from a user.
+func (m *RealmRolesManager) RemoveRolesFromUser(
+	ctx context.Context,
+	userID string,
+	roleNames ...string,
+) error {
+	log.Debug().
+		Str("user_id", userID).
+		Strs("roles", roleNames).
+		Msg("removing realm roles from user")
+
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	roles, err := m.GetRolesByName(ctx, roleNames...)
+	if err != nil {
+		if isNotFound(err) {
+			return ErrUserNotInRole
+		}
+
+		return err
+	}
+
+	err = m.client.Client().DeleteRealmRoleFromUser(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		userID,
+		roles.owned(),
+	)
+	m.rolesCache.dropIfNotFound(err, roleNames...)
+
+	return err
+}
+
+// ContainsRole checks if the user has the given realm role.
+func (m *RealmRolesManager) ContainsRole(claims *recloak.Claims, role string) bool {
+	return claims.RealmAcess.HasRole(role)
+}
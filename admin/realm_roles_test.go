@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak"
+)
+
+// newTestAdminClient creates a client of a fake keycloak admin API, served by
+// the given handler, and a context carrying the admin token.
+func newTestAdminClient(
+	t *testing.T,
+	handler http.Handler,
+) (*recloak.ReCloak, context.Context) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := recloak.NewClient(&recloak.ClientConfig{
+		AuthServerURL: server.URL,
+		Realm:         "test",
+		ClientID:      "client",
+		ClientSecret:  "secret",
+	})
+	require.NoError(t, err)
+
+	token := recloak.Token{Token: &jwt.Token{Raw: "admin", Valid: true}}
+
+	return client, token.WrapContext(context.Background())
+}
+
+func TestRealmRolesManager(t *testing.T) {
+	var lookups int
+	var added []Role
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/realms/test/roles/{name}", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer admin", r.Header.Get("Authorization"))
+
+		lookups++
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Role{
+			ID:   gocloak.StringP("id-" + r.PathValue("name")),
+			Name: gocloak.StringP(r.PathValue("name")),
+		})
+	})
+	mux.HandleFunc("POST /admin/realms/test/users/user/role-mappings/realm", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&added))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /admin/realms/test/users/user/role-mappings/realm", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /admin/realms/test/users/user/role-mappings/realm/composite", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]Role{
+			{Name: gocloak.StringP("admin")},
+			{Name: gocloak.StringP("user")},
+		})
+	})
+
+	client, ctx := newTestAdminClient(t, mux)
+
+	var manager RolesManager = NewRealmRolesManager(client)
+
+	roles, err := manager.GetRolesByName(ctx, "admin", "user")
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	require.Equal(t, "id-user", *roles[1].ID)
+
+	require.NoError(t, manager.AddRolesToUser(ctx, "user", "admin"))
+	require.Equal(t, 2, lookups, "roles should be cached")
+	require.Len(t, added, 1)
+	require.Equal(t, "id-admin", *added[0].ID)
+
+	err = manager.RemoveRolesFromUser(ctx, "user", "admin")
+	require.True(t, isNotFound(err))
+
+	_, err = manager.GetRolesByName(ctx, "admin", "user")
+	require.NoError(t, err)
+	require.Equal(t, 3, lookups, "roles should be dropped once not found")
+
+	effective, err := manager.GetUserEffectiveRoles(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, effective, 2)
+
+	_, err = manager.GetRolesByName(context.Background(), "admin")
+	require.ErrorIs(t, err, recloak.ErrUnauthenticated)
+
+	claims := &recloak.Claims{RealmAcess: recloak.RolesClaim{Roles: []string{"admin"}}}
+	require.True(t, manager.ContainsRole(claims, "admin"))
+	require.False(t, manager.ContainsRole(claims, "user"))
+}
+
+func TestObjectCacheConcurrentUse(t *testing.T) {
+	cache := newObjectCache[Role]()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			name := fmt.Sprintf("role-%d", i%2)
+			cache.put(name, &Role{Name: gocloak.StringP(name)})
+			cache.get(name)
+			cache.drop(name)
+		}()
+	}
+	wg.Wait()
+
+	cache.put("admin", &Role{})
+	cache.dropIfNotFound(&gocloak.APIError{Code: http.StatusBadRequest}, "admin")
+	_, ok := cache.get("admin")
+	require.True(t, ok)
+
+	cache.dropIfNotFound(&gocloak.APIError{Code: http.StatusNotFound}, "admin")
+	_, ok = cache.get("admin")
+	require.False(t, ok)
+}
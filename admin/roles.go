@@ -3,7 +3,6 @@ package admin
 import (
 	"context"
 	"errors"
-	"net/http"
 
 	"github.com/Nerzal/gocloak/v13"
 	"github.com/rs/zerolog/log"
@@ -21,17 +20,46 @@ type Role = gocloak.Role
 // Roles is a slice of Role pointers.
 type Roles []*Role
 
-// RolesManager is a type that provides role management capabilities.
+// RolesManager is a type that provides role management capabilities, over
+// either realm roles or the roles of a client.
+type RolesManager interface {
+	// GetRolesByName returns a list of roles by their names.
+	GetRolesByName(ctx context.Context, roleNames ...string) (Roles, error)
+
+	// GetUserRoles returns a list of roles directly assigned to a user.
+	GetUserRoles(ctx context.Context, userID string) ([]*Role, error)
+
+	// GetUserEffectiveRoles returns a list of roles of a user, including the
+	// ones granted through composite roles and groups.
+	GetUserEffectiveRoles(ctx context.Context, userID string) ([]*Role, error)
+
+	// AddRolesToUser adds roles to a user.
+	AddRolesToUser(ctx context.Context, userID string, roleNames ...string) error
+
+	// RemoveRolesFromUser removes roles from a user.
+	RemoveRolesFromUser(ctx context.Context, userID string, roleNames ...string) error
+
+	// ContainsRole checks if the claims of a user have the given role.
+	ContainsRole(claims *recloak.Claims, role string) bool
+}
+
+var (
+	_ RolesManager = (*ClientRolesManager)(nil)
+	_ RolesManager = (*RealmRolesManager)(nil)
+)
+
+// ClientRolesManager is a type that provides client role management
+// capabilities.
 type ClientRolesManager struct {
 	client     *recloak.ReCloak
-	rolesCache map[string]*Role
+	rolesCache *objectCache[Role]
 }
 
 // NewClientRolesManager creates a new ClientRolesManager instance.
 func NewClientRolesManager(client *recloak.ReCloak) *ClientRolesManager {
 	return &ClientRolesManager{
 		client:     client,
-		rolesCache: make(map[string]*Role),
+		rolesCache: newObjectCache[Role](),
 	}
 }
 
@@ -47,7 +75,7 @@ func (m *ClientRolesManager) GetRolesByName(
 
 	roles := make([]*Role, len(roleNames))
 	for i, roleName := range roleNames {
-		if role, ok := m.rolesCache[roleName]; ok {
+		if role, ok := m.rolesCache.get(roleName); ok {
 			roles[i] = role
 			continue
 		}
@@ -63,7 +91,7 @@ func (m *ClientRolesManager) GetRolesByName(
 			return nil, err
 		}
 
-		m.rolesCache[roleName] = role
+		m.rolesCache.put(roleName, role)
 		roles[i] = role
 	}
 
@@ -92,12 +120,32 @@ func (m *ClientRolesManager) GetUserRoles(
 	}
 
 	for _, role := range roles {
-		m.rolesCache[*role.Name] = role
+		m.rolesCache.put(*role.Name, role)
 	}
 
 	return roles, nil
 }
 
+// GetUserEffectiveRoles returns a list of client roles by user ID, including
+// the ones granted through composite roles and groups.
+func (m *ClientRolesManager) GetUserEffectiveRoles(
+	ctx context.Context,
+	userID string,
+) ([]*Role, error) {
+	token, repr, err := m.getTokenAndRepresentation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.client.Client().GetCompositeClientRolesByUserID(
+		ctx,
+		token.Raw,
+		m.client.Config().Realm,
+		*repr.ID,
+		userID,
+	)
+}
+
 // AddRolesToUser adds roles to a user.
 func (m *ClientRolesManager) AddRolesToUser(
 	ctx context.Context,
@@ -119,7 +167,7 @@ func (m *ClientRolesManager) AddRolesToUser(
 		return err
 	}
 
-	return m.client.Client().AddClientRolesToUser(
+	err = m.client.Client().AddClientRolesToUser(
 		ctx,
 		token.Raw,
 		m.client.Config().Realm,
@@ -127,6 +175,9 @@ func (m *ClientRolesManager) AddRolesToUser(
 		userID,
 		roles.owned(),
 	)
+	m.rolesCache.dropIfNotFound(err, roleNames...)
+
+	return err
 }
 
 // RemoveRolesFromUser removes roles from a user.
@@ -147,15 +198,14 @@ func (m *ClientRolesManager) RemoveRolesFromUser(
 
 	roles, err := m.GetRolesByName(ctx, roleNames...)
 	if err != nil {
-		var kcErr *gocloak.APIError
-		if errors.As(err, &kcErr) && kcErr.Code == http.StatusNotFound {
+		if isNotFound(err) {
 			return ErrUserNotInRole
 		}
 
 		return err
 	}
 
-	return m.client.Client().DeleteClientRolesFromUser(
+	err = m.client.Client().DeleteClientRolesFromUser(
 		ctx,
 		token.Raw,
 		m.client.Config().Realm,
@@ -163,6 +213,9 @@ func (m *ClientRolesManager) RemoveRolesFromUser(
 		userID,
 		roles.owned(),
 	)
+	m.rolesCache.dropIfNotFound(err, roleNames...)
+
+	return err
 }
 
 // ContainsRole checks if the user has the given role in the client.
@@ -173,17 +226,7 @@ func (m *ClientRolesManager) ContainsRole(claims *recloak.Claims, role string) b
 func (m *ClientRolesManager) getTokenAndRepresentation(
 	ctx context.Context,
 ) (recloak.Token, *gocloak.Client, error) {
-	token, err := recloak.TokenFromContext(ctx)
-	if err != nil {
-		return recloak.Token{}, nil, err
-	}
-
-	repr, err := m.client.GetRepresentation(ctx)
-	if err != nil {
-		return recloak.Token{}, nil, err
-	}
-
-	return token, repr, nil
+	return getTokenAndRepresentation(ctx, m.client)
 }
 
 // Returns an owned copy of the underlying `gocloak.Role` slice.
@@ -0,0 +1,419 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+)
+
+// RolesReconcilerOptions is a struct that holds the options of a
+// `RolesReconciler`.
+type RolesReconcilerOptions struct {
+	// Whether to warn about client roles that exist in keycloak but are not
+	// declared in the configuration. They are never deleted.
+	FlagUnknown bool
+}
+
+// RolesReconciler is a type that provisions the client roles declared in an
+// authorization configuration, creating missing roles and updating the
+// descriptions and composites of existing ones.
+//
+// It also warns about roles checked by the policies of the configuration that
+// are not declared, or that do not exist in the realm in case of realm roles.
+type RolesReconciler struct {
+	client  *recloak.ReCloak
+	options RolesReconcilerOptions
+}
+
+// NewRolesReconciler creates a new RolesReconciler instance.
+func NewRolesReconciler(
+	client *recloak.ReCloak,
+	options RolesReconcilerOptions,
+) *RolesReconciler {
+	return &RolesReconciler{client: client, options: options}
+}
+
+// Plan compares the roles declared in the given configuration with the roles
+// of the client, and returns the changes needed to reconcile them without
+// applying them, which serves as a dry run.
+//
+// The admin token is taken from the context.
+func (r *RolesReconciler) Plan(
+	ctx context.Context,
+	config *authz.AuthzConfig,
+) (*SyncPlan, error) {
+	engine, err := authz.NewEngine(config)
+	if err != nil {
+		return nil, err
+	}
+
+	token, repr, err := getTokenAndRepresentation(ctx, r.client)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := r.fetchRoles(ctx, token.Raw, *repr.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	realmRoles, err := r.client.Client().GetRealmRoles(
+		ctx,
+		token.Raw,
+		r.client.Config().Realm,
+		gocloak.GetRoleParams{BriefRepresentation: gocloak.BoolP(true)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := diffRoles(desiredRoles(config.Roles), existing, r.options.FlagUnknown)
+
+	for _, role := range engine.UndeclaredRoles() {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+			"client role `%s` is checked by policies but not declared",
+			role,
+		))
+	}
+
+	for _, role := range engine.RoleReferences().RealmRoles {
+		if !slices.ContainsFunc(realmRoles, func(realmRole *Role) bool {
+			return gocloak.PString(realmRole.Name) == role
+		}) {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+				"realm role `%s` is checked by policies but does not exist",
+				role,
+			))
+		}
+	}
+
+	for _, warning := range plan.Warnings {
+		log.Warn().Msg(warning)
+	}
+
+	return plan, nil
+}
+
+// Apply applies the changes of the given plan. Roles are created and updated
+// before their composites are set, so that roles may be composed of roles
+// created by the same plan.
+//
+// The admin token is taken from the context.
+func (r *RolesReconciler) Apply(ctx context.Context, plan *SyncPlan) error {
+	token, repr, err := getTokenAndRepresentation(ctx, r.client)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range plan.ops {
+		log.Debug().Stringer("change", op.change).Msg("applying role change")
+
+		if err := r.applyRole(ctx, token.Raw, *repr.ID, op); err != nil {
+			return fmt.Errorf("could not apply `%s`: %w", op.change, err)
+		}
+	}
+
+	for _, op := range plan.ops {
+		if err := r.applyComposites(ctx, token.Raw, *repr.ID, op); err != nil {
+			return fmt.Errorf("could not apply `%s`: %w", op.change, err)
+		}
+	}
+
+	return nil
+}
+
+// Reconcile plans and applies the changes needed to reconcile the roles of the
+// given configuration, returning the applied plan.
+func (r *RolesReconciler) Reconcile(
+	ctx context.Context,
+	config *authz.AuthzConfig,
+) (*SyncPlan, error) {
+	plan, err := r.Plan(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, r.Apply(ctx, plan)
+}
+
+func (r *RolesReconciler) applyRole(
+	ctx context.Context,
+	token string,
+	clientID string,
+	op syncOp,
+) error {
+	role := Role{
+		Name:        gocloak.StringP(op.role.name),
+		Description: gocloak.StringP(op.role.description),
+	}
+
+	if op.change.Action == SyncActionCreate {
+		_, err := r.client.Client().CreateClientRole(
+			ctx,
+			token,
+			r.client.Config().Realm,
+			clientID,
+			role,
+		)
+
+		return err
+	}
+
+	if op.role.description == op.currentRole.description {
+		return nil
+	}
+
+	return r.client.Client().UpdateRole(ctx, token, r.client.Config().Realm, clientID, role)
+}
+
+func (r *RolesReconciler) applyComposites(
+	ctx context.Context,
+	token string,
+	clientID string,
+	op syncOp,
+) error {
+	var current []string
+	if op.currentRole != nil {
+		current = op.currentRole.composites
+	}
+
+	var added, removed []string
+	for _, composite := range op.role.composites {
+		if !slices.Contains(current, composite) {
+			added = append(added, composite)
+		}
+	}
+
+	for _, composite := range current {
+		if !slices.Contains(op.role.composites, composite) {
+			removed = append(removed, composite)
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	role, err := r.client.Client().GetClientRole(
+		ctx,
+		token,
+		r.client.Config().Realm,
+		clientID,
+		op.role.name,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(added) > 0 {
+		roles, err := r.getRoles(ctx, token, clientID, added)
+		if err != nil {
+			return err
+		}
+
+		err = r.client.Client().AddClientRoleComposite(
+			ctx,
+			token,
+			r.client.Config().Realm,
+			*role.ID,
+			roles,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(removed) > 0 {
+		roles, err := r.getRoles(ctx, token, clientID, removed)
+		if err != nil {
+			return err
+		}
+
+		return r.client.Client().DeleteClientRoleComposite(
+			ctx,
+			token,
+			r.client.Config().Realm,
+			*role.ID,
+			roles,
+		)
+	}
+
+	return nil
+}
+
+// getRoles returns the client roles of the given names.
+func (r *RolesReconciler) getRoles(
+	ctx context.Context,
+	token string,
+	clientID string,
+	names []string,
+) ([]Role, error) {
+	roles := make([]Role, len(names))
+	for i, name := range names {
+		role, err := r.client.Client().GetClientRole(
+			ctx,
+			token,
+			r.client.Config().Realm,
+			clientID,
+			name,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		roles[i] = *role
+	}
+
+	return roles, nil
+}
+
+// fetchRoles fetches the current roles of the client, along with their client
+// composites.
+func (r *RolesReconciler) fetchRoles(
+	ctx context.Context,
+	token string,
+	clientID string,
+) (map[string]*syncRole, error) {
+	client := r.client.Client()
+	realm := r.client.Config().Realm
+
+	roles, err := client.GetClientRoles(ctx, token, realm, clientID, gocloak.GetRoleParams{
+		BriefRepresentation: gocloak.BoolP(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*syncRole, len(roles))
+	for _, role := range roles {
+		synced := &syncRole{
+			id:          gocloak.PString(role.ID),
+			name:        gocloak.PString(role.Name),
+			description: gocloak.PString(role.Description),
+		}
+
+		if gocloak.PBool(role.Composite) {
+			composites, err := client.GetCompositeClientRolesByRoleID(
+				ctx,
+				token,
+				realm,
+				clientID,
+				synced.id,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, composite := range composites {
+				synced.composites = append(synced.composites, gocloak.PString(composite.Name))
+			}
+		}
+
+		synced.composites = sortedUnique(synced.composites)
+		existing[synced.name] = synced
+	}
+
+	return existing, nil
+}
+
+// syncRole is a client role, as declared or as it exists in keycloak.
+type syncRole struct {
+	id          string
+	name        string
+	description string
+	composites  []string
+}
+
+func (r *syncRole) equal(other *syncRole) bool {
+	return r.description == other.description &&
+		slices.Equal(r.composites, other.composites)
+}
+
+func (r *syncRole) details() string {
+	var details []string
+	if r.description != "" {
+		details = append(details, "description: "+r.description)
+	}
+	if len(r.composites) > 0 {
+		details = append(details, "composites: "+strings.Join(r.composites, ", "))
+	}
+
+	return strings.Join(details, ", ")
+}
+
+// desiredRoles returns the state that the given declared roles map to.
+func desiredRoles(roles []authz.ClientRole) map[string]*syncRole {
+	desired := make(map[string]*syncRole, len(roles))
+	for _, role := range roles {
+		desired[role.Name] = &syncRole{
+			name:        role.Name,
+			description: role.Description,
+			composites:  sortedUnique(role.Composites),
+		}
+	}
+
+	return desired
+}
+
+// diffRoles returns the plan that changes the existing roles into the desired
+// ones. Existing roles that are not desired are never deleted, and are only
+// reported as warnings if `flagUnknown` is set.
+func diffRoles(desired, existing map[string]*syncRole, flagUnknown bool) *SyncPlan {
+	plan := &SyncPlan{}
+	add := func(op syncOp) {
+		plan.Changes = append(plan.Changes, op.change)
+		plan.ops = append(plan.ops, op)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		role := desired[name]
+		current, ok := existing[name]
+
+		switch {
+		case !ok:
+			add(syncOp{
+				change: SyncChange{
+					Action:  SyncActionCreate,
+					Object:  SyncObjectRole,
+					Name:    name,
+					Details: role.details(),
+				},
+				role: role,
+			})
+
+		case !role.equal(current):
+			add(syncOp{
+				change: SyncChange{
+					Action:  SyncActionUpdate,
+					Object:  SyncObjectRole,
+					Name:    name,
+					Details: role.details(),
+				},
+				id:          current.id,
+				role:        role,
+				currentRole: current,
+			})
+		}
+	}
+
+	if flagUnknown {
+		for _, name := range slices.Sorted(maps.Keys(existing)) {
+			if _, ok := desired[name]; !ok {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+					"client role `%s` exists but is not declared",
+					name,
+				))
+			}
+		}
+	}
+
+	return plan
+}
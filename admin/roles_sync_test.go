@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak/authz"
+)
+
+func TestDiffRoles(t *testing.T) {
+	desired := desiredRoles([]authz.ClientRole{
+		{Name: "orders:read", Description: "Read orders"},
+		{Name: "orders:write", Description: "Write orders"},
+		{Name: "orders:admin", Composites: []string{"orders:write", "orders:read"}},
+		{Name: "health"},
+	})
+
+	existing := map[string]*syncRole{
+		"orders:read":  {id: "id-read", name: "orders:read", description: "Read orders"},
+		"orders:write": {id: "id-write", name: "orders:write", description: "Write"},
+		"orders:admin": {id: "id-admin", name: "orders:admin", composites: []string{"orders:read"}},
+		"legacy":       {id: "id-legacy", name: "legacy"},
+	}
+
+	plan := diffRoles(desired, existing, false)
+	require.Equal(t, ""+
+		"+ role health\n"+
+		"~ role orders:admin (composites: orders:read, orders:write)\n"+
+		"~ role orders:write (description: Write orders)\n",
+		plan.String(),
+	)
+	require.Equal(t, "id-admin", plan.ops[1].id)
+	require.Equal(t, existing["orders:admin"], plan.ops[1].currentRole)
+
+	plan = diffRoles(desired, existing, true)
+	require.Equal(t, []string{"client role `legacy` exists but is not declared"}, plan.Warnings)
+	require.Contains(t, plan.String(), "! client role `legacy` exists but is not declared\n")
+
+	require.True(t, diffRoles(desired, desired, true).IsEmpty())
+}
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Nerzal/gocloak/v13"
+
+	"github.com/real-evolution/recloak"
+)
+
+// getTokenAndRepresentation returns the token of the caller from the context,
+// used to call the admin API, along with the representation of the client.
+func getTokenAndRepresentation(
+	ctx context.Context,
+	client *recloak.ReCloak,
+) (recloak.Token, *gocloak.Client, error) {
+	token, err := recloak.TokenFromContext(ctx)
+	if err != nil {
+		return recloak.Token{}, nil, err
+	}
+
+	repr, err := client.GetRepresentation(ctx)
+	if err != nil {
+		return recloak.Token{}, nil, err
+	}
+
+	return token, repr, nil
+}
+
+// isNotFound checks whether the given error is a not found keycloak error.
+func isNotFound(err error) bool {
+	var kcErr *gocloak.APIError
+
+	return errors.As(err, &kcErr) && kcErr.Code == http.StatusNotFound
+}
+
+// pageSize is the number of objects requested per page when listing all the
+// objects of a kind, as keycloak returns a limited number of them by default.
+const pageSize = 100
+
+// fetchAll fetches all the objects of a listing by calling the given fetch
+// function with successive pages, until a page is not full.
+func fetchAll[T any](fetch func(first, max int) ([]T, error)) ([]T, error) {
+	var all []T
+
+	for first := 0; ; first += pageSize {
+		page, err := fetch(first, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, page...)
+
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+// objectCache is a cache of keycloak objects, e.g. roles or groups, by name or
+// path. It is safe for concurrent use.
+type objectCache[T any] struct {
+	mu      sync.RWMutex
+	entries map[string]*T
+}
+
+func newObjectCache[T any]() *objectCache[T] {
+	return &objectCache[T]{entries: make(map[string]*T)}
+}
+
+func (c *objectCache[T]) get(key string) (*T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	value, ok := c.entries[key]
+
+	return value, ok
+}
+
+func (c *objectCache[T]) put(key string, value *T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = value
+}
+
+// drop drops the objects of the given keys.
+func (c *objectCache[T]) drop(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+// dropTree drops the object of the given path, and the objects of the paths
+// below it, e.g. the subgroups of a group.
+func (c *objectCache[T]) dropTree(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if key == path || strings.HasPrefix(key, path+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// dropIfNotFound drops the objects of the given keys if the given error is a
+// not found keycloak error, as they may have been deleted.
+func (c *objectCache[T]) dropIfNotFound(err error, keys ...string) {
+	if isNotFound(err) {
+		c.drop(keys...)
+	}
+}
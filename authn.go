@@ -41,34 +41,93 @@ type Claims struct {
 	jwt.RegisteredClaims
 
 	// Custom claims
+	AuthorizedParty   string                `json:"azp,omitempty"`
+	SessionID         string                `json:"sid,omitempty"`
 	PreferredUsername string                `json:"preferred_username"`
 	RealmAcess        RolesClaim            `json:"realm_access,omitempty"`
 	ResourceAcess     map[string]RolesClaim `json:"resource_access,omitempty"`
+
+	// All claims of the token, including the ones above.
+	Extra map[string]any `json:"-"`
 }
 
-// DecodeAccessToken decodes a bearer access token and returns a Token instance
+// DecodeAccessToken decodes a bearer access token and returns a Token instance.
+//
+// The token signature is verified locally against the cached realm keys, and
+// its registered claims are validated according to `ClientConfig.Validation`.
+// Tokens without an `exp` claim are rejected.
 func (c *ReCloak) DecodeAccessToken(
 	ctx context.Context,
 	tokenString string,
 ) (Token, error) {
-	if err := c.RefreshIfExpired(ctx); err != nil {
-		return Token{}, err
-	}
+	return c.DecodeAccessTokenWithOptions(ctx, tokenString, c.config.Validation)
+}
 
+// DecodeAccessTokenWithOptions decodes a bearer access token the same way
+// `ReCloak.DecodeAccessToken` does, validating its registered claims according
+// to the given options instead of the ones of the client.
+func (c *ReCloak) DecodeAccessTokenWithOptions(
+	ctx context.Context,
+	tokenString string,
+	options ValidationOptions,
+) (Token, error) {
 	claims := &Claims{}
-	token, err := c.Client().DecodeAccessTokenCustomClaims(
-		ctx,
-		tokenString,
-		c.config.Realm,
-		claims,
-	)
+	token, err := c.decodeAccessToken(ctx, tokenString, claims, &options)
 	if err != nil {
 		return Token{}, err
 	}
 
+	if claims.Extra, err = decodeExtraClaims(token); err != nil {
+		return Token{}, err
+	}
+
 	return Token{token, claims}, nil
 }
 
+// DecodeAccessTokenAs decodes a bearer access token into a user-defined claims
+// type, verifying it the same way `ReCloak.DecodeAccessToken` does.
+func DecodeAccessTokenAs[T any, PT interface {
+	*T
+	jwt.Claims
+}](
+	ctx context.Context,
+	c *ReCloak,
+	tokenString string,
+) (*jwt.Token, PT, error) {
+	claims := PT(new(T))
+	token, err := c.decodeAccessToken(ctx, tokenString, claims, &c.config.Validation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, claims, nil
+}
+
+func (c *ReCloak) decodeAccessToken(
+	ctx context.Context,
+	tokenString string,
+	claims jwt.Claims,
+	options *ValidationOptions,
+) (*jwt.Token, error) {
+	opts, err := options.parserOptions(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, jwt.WithExpirationRequired())
+
+	token, err := c.keys.Parse(ctx, tokenString, claims, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := options.validateAudience(c.config, claims); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
 // WrapContext wraps the claims in the context.
 func (t Token) WrapContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, TokenContextKey, t)
@@ -144,3 +203,7 @@ func (c *Claims) GetSubject() (string, error) {
 func (c *Claims) GetAudience() (jwt.ClaimStrings, error) {
   return c.Audience, nil
 }
+
+func (c *Claims) GetAuthorizedParty() (string, error) {
+  return c.AuthorizedParty, nil
+}
@@ -0,0 +1,286 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+)
+
+// requestIDContextKey is a context key for the request ID
+type requestIDContextKey struct{}
+
+// ErrAuditSinkClosed is returned when recording to a closed audit sink.
+var ErrAuditSinkClosed = errors.New("audit sink is closed")
+
+// AuditRecord is an audit log entry of an authorization decision.
+type AuditRecord struct {
+	Timestamp    time.Time     `json:"timestamp"`
+	RequestID    string        `json:"requestId,omitempty"`
+	Subject      string        `json:"sub,omitempty"`
+	Username     string        `json:"preferred_username,omitempty"`
+	Path         string        `json:"path"`
+	Resource     string        `json:"resource,omitempty"`
+	Allowed      bool          `json:"allowed"`
+	Shadowed     bool          `json:"shadowed,omitempty"`
+	Mode         string        `json:"enforcementMode"`
+	Policies     []string      `json:"policies,omitempty"`
+	FailedPolicy string        `json:"failedPolicy,omitempty"`
+	Reason       string        `json:"reason,omitempty"`
+	Duration     time.Duration `json:"duration"`
+}
+
+// AuditSink is a type that records authorization decisions.
+type AuditSink interface {
+	// Record records the given audit record.
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// AuditSinkFunc is a function that implements `AuditSink`.
+type AuditSinkFunc func(ctx context.Context, record AuditRecord) error
+
+func (f AuditSinkFunc) Record(ctx context.Context, record AuditRecord) error {
+	return f(ctx, record)
+}
+
+// NewAuditRecord creates a new audit record of the given decision, made for
+// the given claims, which may be nil if the token could not be decoded.
+func NewAuditRecord(
+	ctx context.Context,
+	claims *recloak.Claims,
+	decision *Decision,
+) AuditRecord {
+	record := AuditRecord{
+		Timestamp: decision.Timestamp,
+		RequestID: RequestIDFromContext(ctx),
+		Path:      decision.Path,
+		Resource:  decision.Resource,
+		Allowed:   decision.Allowed,
+		Shadowed:  decision.Shadowed,
+		Mode:      decision.Mode.String(),
+		Policies:  decision.Policies(),
+		Duration:  decision.Duration,
+	}
+
+	if claims != nil {
+		record.Subject = claims.Subject
+		record.Username = claims.PreferredUsername
+	}
+
+	if decision.FailedClause != nil {
+		record.FailedPolicy = decision.FailedClause.Policy
+	}
+
+	if decision.Err != nil {
+		record.Reason = decision.Err.Error()
+	}
+
+	return record
+}
+
+// WithRequestID returns a context that carries the given request ID, which is
+// included in audit records.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID of the context, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+
+	return requestID
+}
+
+// WriterAuditSink is an audit sink that writes records as JSON lines to a
+// writer. It is safe for concurrent use.
+type WriterAuditSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewWriterAuditSink creates a new audit sink that writes to the given writer.
+func NewWriterAuditSink(w io.Writer) *WriterAuditSink {
+	return &WriterAuditSink{encoder: json.NewEncoder(w)}
+}
+
+func (s *WriterAuditSink) Record(_ context.Context, record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.encoder.Encode(record)
+}
+
+// FileAuditSink is an audit sink that appends records as JSON lines to a file.
+type FileAuditSink struct {
+	*WriterAuditSink
+
+	file *os.File
+}
+
+// NewFileAuditSink creates a new audit sink that appends to the file at the
+// given path, creating it if it does not exist.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAuditSink{
+		WriterAuditSink: NewWriterAuditSink(file),
+		file:            file,
+	}, nil
+}
+
+// Close closes the underlying file.
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// ChannelAuditSink is an audit sink that sends records to a channel without
+// blocking, dropping them if the channel is full.
+type ChannelAuditSink struct {
+	records chan<- AuditRecord
+	dropped atomic.Uint64
+}
+
+// NewChannelAuditSink creates a new audit sink that sends to the given channel.
+func NewChannelAuditSink(records chan<- AuditRecord) *ChannelAuditSink {
+	return &ChannelAuditSink{records: records}
+}
+
+func (s *ChannelAuditSink) Record(_ context.Context, record AuditRecord) error {
+	select {
+	case s.records <- record:
+	default:
+		s.dropped.Add(1)
+	}
+
+	return nil
+}
+
+// Dropped returns the number of records dropped because the channel was full.
+func (s *ChannelAuditSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// AsyncAuditSink is an audit sink that buffers records and forwards them to
+// another sink in the background, so that recording never blocks requests.
+// Records are dropped when the buffer is full.
+type AsyncAuditSink struct {
+	next    AuditSink
+	records chan AuditRecord
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+// NewAsyncAuditSink creates a new asynchronous audit sink with the given
+// buffer size, forwarding records to the given sink.
+func NewAsyncAuditSink(next AuditSink, bufferSize int) *AsyncAuditSink {
+	s := &AsyncAuditSink{
+		next:    next,
+		records: make(chan AuditRecord, bufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *AsyncAuditSink) Record(_ context.Context, record AuditRecord) error {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+
+	if s.closed {
+		return ErrAuditSinkClosed
+	}
+
+	select {
+	case s.records <- record:
+	default:
+		s.dropped.Add(1)
+	}
+
+	return nil
+}
+
+// Dropped returns the number of records dropped because the buffer was full.
+func (s *AsyncAuditSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops accepting records and waits until the buffered ones are
+// forwarded.
+func (s *AsyncAuditSink) Close() error {
+	s.closeMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.closeMu.Unlock()
+
+	<-s.done
+
+	return nil
+}
+
+func (s *AsyncAuditSink) run() {
+	defer close(s.done)
+
+	for record := range s.records {
+		if err := s.next.Record(context.Background(), record); err != nil {
+			log.Warn().Err(err).Msg("could not record audit record")
+		}
+	}
+}
+
+// SamplingAuditSink is an audit sink that forwards all denied decisions,
+// including the ones that would have been denied in shadow mode, and only a
+// sample of allowed decisions, to another sink.
+type SamplingAuditSink struct {
+	next      AuditSink
+	allowRate float64
+}
+
+// NewSamplingAuditSink creates a new sampling audit sink that forwards the
+// given fraction (between 0 and 1) of allowed decisions.
+func NewSamplingAuditSink(next AuditSink, allowRate float64) *SamplingAuditSink {
+	return &SamplingAuditSink{next: next, allowRate: allowRate}
+}
+
+func (s *SamplingAuditSink) Record(ctx context.Context, record AuditRecord) error {
+	if record.Allowed && !record.Shadowed && rand.Float64() >= s.allowRate {
+		return nil
+	}
+
+	return s.next.Record(ctx, record)
+}
+
+// MultiAuditSink is an audit sink that forwards records to multiple sinks.
+type MultiAuditSink []AuditSink
+
+func (s MultiAuditSink) Record(ctx context.Context, record AuditRecord) error {
+	var errs []error
+	for _, sink := range s {
+		if err := sink.Record(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package authz
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak"
+)
+
+func TestNewAuditRecord(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "request-1")
+	claims := &recloak.Claims{PreferredUsername: "john"}
+	claims.Subject = "user-1"
+
+	decision := Decision{
+		Path:     "tenants.docs.get",
+		Resource: "tenants.*.get",
+		Clauses: []Clause{
+			{Policy: "is_user", Resource: "tenants"},
+			{Resource: "tenants.*.get"},
+		},
+		FailedClause: &Clause{Policy: "is_user", Resource: "tenants"},
+		Mode:         EnforcementModeEnforcing,
+		Err:          ErrUnauthorized,
+		Timestamp:    time.Now(),
+		Duration:     time.Millisecond,
+	}
+
+	record := NewAuditRecord(ctx, claims, &decision)
+	require.Equal(t, "request-1", record.RequestID)
+	require.Equal(t, "user-1", record.Subject)
+	require.Equal(t, "john", record.Username)
+	require.Equal(t, decision.Path, record.Path)
+	require.Equal(t, decision.Resource, record.Resource)
+	require.False(t, record.Allowed)
+	require.Equal(t, "enforcing", record.Mode)
+	require.Equal(t, []string{"is_user"}, record.Policies)
+	require.Equal(t, "is_user", record.FailedPolicy)
+	require.Equal(t, ErrUnauthorized.Error(), record.Reason)
+	require.Equal(t, decision.Duration, record.Duration)
+
+	record = NewAuditRecord(context.Background(), nil, &Decision{Allowed: true})
+	require.Empty(t, record.RequestID)
+	require.Empty(t, record.Subject)
+	require.Empty(t, record.FailedPolicy)
+	require.Empty(t, record.Reason)
+}
+
+func TestWriterAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterAuditSink(&buf)
+
+	require.NoError(t, sink.Record(context.Background(), AuditRecord{Path: "a"}))
+	require.NoError(t, sink.Record(context.Background(), AuditRecord{Path: "b"}))
+
+	require.Equal(t, []string{"a", "b"}, readAuditPaths(t, &buf))
+}
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, p := range []string{"a", "b"} {
+		sink, err := NewFileAuditSink(path)
+		require.NoError(t, err)
+		require.NoError(t, sink.Record(context.Background(), AuditRecord{Path: p}))
+		require.NoError(t, sink.Close())
+	}
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, readAuditPaths(t, bytes.NewReader(content)))
+}
+
+func TestChannelAuditSink(t *testing.T) {
+	records := make(chan AuditRecord, 1)
+	sink := NewChannelAuditSink(records)
+
+	require.NoError(t, sink.Record(context.Background(), AuditRecord{Path: "a"}))
+	require.NoError(t, sink.Record(context.Background(), AuditRecord{Path: "b"}))
+
+	require.Equal(t, "a", (<-records).Path)
+	require.Equal(t, uint64(1), sink.Dropped())
+}
+
+func TestAsyncAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewAsyncAuditSink(NewWriterAuditSink(&buf), 16)
+
+	for _, p := range []string{"a", "b", "c"} {
+		require.NoError(t, sink.Record(context.Background(), AuditRecord{Path: p}))
+	}
+
+	require.NoError(t, sink.Close())
+	require.NoError(t, sink.Close())
+	require.Equal(t, []string{"a", "b", "c"}, readAuditPaths(t, &buf))
+	require.Zero(t, sink.Dropped())
+
+	err := sink.Record(context.Background(), AuditRecord{Path: "d"})
+	require.ErrorIs(t, err, ErrAuditSinkClosed)
+}
+
+func TestSamplingAuditSink(t *testing.T) {
+	var count int
+	counter := AuditSinkFunc(func(context.Context, AuditRecord) error {
+		count++
+		return nil
+	})
+
+	sink := NewSamplingAuditSink(counter, 0)
+	for range 10 {
+		require.NoError(t, sink.Record(context.Background(), AuditRecord{Allowed: true}))
+		require.NoError(t, sink.Record(context.Background(), AuditRecord{Allowed: false}))
+	}
+	require.Equal(t, 10, count)
+
+	// decisions that would have been denied in shadow mode are all forwarded
+	count = 0
+	for range 10 {
+		require.NoError(t, sink.Record(
+			context.Background(),
+			AuditRecord{Allowed: true, Shadowed: true},
+		))
+	}
+	require.Equal(t, 10, count)
+
+	count = 0
+	sink = NewSamplingAuditSink(counter, 1)
+	for range 10 {
+		require.NoError(t, sink.Record(context.Background(), AuditRecord{Allowed: true}))
+	}
+	require.Equal(t, 10, count)
+}
+
+func TestMultiAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	errSink := errors.New("sink failed")
+
+	sink := MultiAuditSink{
+		AuditSinkFunc(func(context.Context, AuditRecord) error { return errSink }),
+		NewWriterAuditSink(&buf),
+	}
+
+	err := sink.Record(context.Background(), AuditRecord{Path: "a"})
+	require.ErrorIs(t, err, errSink)
+	require.Equal(t, []string{"a"}, readAuditPaths(t, &buf))
+}
+
+func readAuditPaths(t *testing.T, r io.Reader) []string {
+	var paths []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		paths = append(paths, record.Path)
+	}
+	require.NoError(t, scanner.Err())
+
+	return paths
+}
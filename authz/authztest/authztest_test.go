@@ -0,0 +1,108 @@
+package authztest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak/authz"
+)
+
+func TestRunSuite(t *testing.T) {
+	RunSuite(t, "testdata/config.yaml", "testdata/suite.yaml")
+}
+
+func TestReport(t *testing.T) {
+	runner, err := LoadRunner("testdata/config.yaml")
+	require.NoError(t, err)
+
+	suite, err := ParseSuite([]byte(`
+cases:
+  - name: writers can list orders
+    path: orders.list
+    claims:
+      clientRoles: [orders:write]
+    expect: allow
+  - name: readers cannot list orders
+    path: orders.list
+    claims:
+      clientRoles: [orders:read]
+    expect: deny
+`))
+	require.NoError(t, err)
+
+	report := runner.Run(suite)
+	require.False(t, report.Passed())
+	require.Len(t, report.Failures(), 2)
+
+	failure := report.Failures()[0]
+	require.False(t, failure.Allowed)
+	require.Equal(t, `(InRole("orders:read") || InRealmRole("admin"))`, failure.Expression)
+	require.Contains(t, failure.String(), "orders.list expected to allow, but got deny")
+	require.Contains(t, report.String(), "0 passed, 2 failed\n")
+}
+
+func TestRunnerEnforcesPolicies(t *testing.T) {
+	shadow := authz.EnforcementModeShadow
+
+	config := authz.AuthzConfig{
+		PathSeparator:   ".",
+		EnforcementMode: authz.EnforcementModePermissive,
+		Resources: []authz.Resource{
+			{
+				Name:            "orders",
+				EnforcementMode: &shadow,
+				Policy: &authz.PolicySpec{
+					InPlace: &authz.Policy{Expression: `InRole("orders:read")`},
+				},
+			},
+		},
+	}
+
+	runner, err := NewRunner(&config)
+	require.NoError(t, err)
+
+	suite, err := ParseSuite([]byte(`
+cases:
+  - name: readers can read orders
+    path: orders
+    claims:
+      clientRoles: [orders:read]
+    expect: allow
+  - name: others cannot read orders in shadow mode
+    path: orders
+    expect: deny
+  - name: unmapped paths are denied in permissive mode
+    path: invoices
+    expect: deny
+`))
+	require.NoError(t, err)
+
+	report := runner.Run(suite)
+	require.True(t, report.Passed(), report.String())
+
+	// the configuration itself is left as is
+	require.Equal(t, authz.EnforcementModePermissive, config.EnforcementMode)
+	require.Equal(t, authz.EnforcementModeShadow, *config.Resources[0].EnforcementMode)
+}
+
+func TestParseSuite(t *testing.T) {
+	_, err := ParseSuite([]byte(`cases: [{name: a, path: b, expect: maybe}]`))
+	require.Error(t, err)
+
+	_, err = ParseSuite([]byte(`cases: [{name: a, expect: allow}]`))
+	require.Error(t, err)
+
+	_, err = ParseSuite([]byte(`cases: [{name: a, path: b}]`))
+	require.EqualError(t, err, "expectation of case `a` is missing")
+
+	_, err = ParseSuite([]byte(`cases: [{name: a, path: b, expect: }]`))
+	require.EqualError(t, err, "expectation of case `a` is missing")
+
+	_, err = ParseSuite([]byte(`cases: [{name: a, path: b, expect: ""}]`))
+	require.Error(t, err)
+
+	suite, err := ParseSuite([]byte(`cases: [{name: a, path: b, expect: Deny}]`))
+	require.NoError(t, err)
+	require.Equal(t, ExpectDeny, suite.Cases[0].Expect)
+}
@@ -0,0 +1,196 @@
+package authztest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/real-evolution/recloak/authz"
+	"github.com/real-evolution/recloak/config"
+)
+
+// Runner is a type that evaluates test cases against an authorization
+// configuration.
+type Runner struct {
+	config *authz.AuthzConfig
+	engine *authz.Engine
+}
+
+// Result is the outcome of a test case.
+type Result struct {
+	// The case.
+	Case Case
+
+	// Whether the request was allowed.
+	Allowed bool
+
+	// The error returned when the request was denied.
+	Err error
+
+	// The composed policy expression of the resource of the path, if any.
+	Expression string
+}
+
+// Report is the outcome of a test suite.
+type Report struct {
+	// The name of the suite.
+	Suite string
+
+	// The results of the cases, in order.
+	Results []Result
+}
+
+// NewRunner creates a new runner of the given configuration. Cases are
+// evaluated as if every resource were in enforcing mode, so that they test the
+// policies being rolled out in shadow or permissive mode too.
+func NewRunner(config *authz.AuthzConfig) (*Runner, error) {
+	engine, err := authz.NewEngine(enforced(config))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Runner{config: config, engine: engine}, nil
+}
+
+// LoadRunner creates a new runner of the authorization configuration of the
+// recloak configuration file at the given path.
+func LoadRunner(path string) (*Runner, error) {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRunner(&cfg.Authz)
+}
+
+// Run evaluates every case of the given suite.
+func (r *Runner) Run(suite *Suite) Report {
+	report := Report{
+		Suite:   suite.Name,
+		Results: make([]Result, len(suite.Cases)),
+	}
+
+	for i, c := range suite.Cases {
+		report.Results[i] = r.RunCase(c)
+	}
+
+	return report
+}
+
+// RunCase evaluates a single case through `Engine.Authorize`, in enforcing
+// mode.
+func (r *Runner) RunCase(c Case) Result {
+	claims := c.Claims.Claims(r.config.ClientID)
+
+	err := r.engine.Authorize(c.Path, claims, c.Request)
+	result := Result{
+		Case:    c,
+		Allowed: err == nil,
+		Err:     err,
+	}
+
+	if !result.Passed() {
+		result.Expression = r.engine.Decide(c.Path, claims, c.Request).Expression
+	}
+
+	return result
+}
+
+// Passed checks whether the outcome matches the expectation of the case.
+func (r Result) Passed() bool {
+	return r.Allowed == (r.Case.Expect == ExpectAllow)
+}
+
+// String returns a description of the result, explaining failures.
+func (r Result) String() string {
+	actual := ExpectAllow
+	if !r.Allowed {
+		actual = ExpectDeny
+	}
+
+	if r.Passed() {
+		return fmt.Sprintf("ok: %s", r.Case.Name)
+	}
+
+	msg := fmt.Sprintf(
+		"FAIL: %s: %s expected to %s, but got %s",
+		r.Case.Name,
+		r.Case.Path,
+		r.Case.Expect,
+		actual,
+	)
+
+	if r.Err != nil {
+		msg += fmt.Sprintf("\n\terror: %s", r.Err)
+	}
+
+	if r.Expression != "" {
+		msg += fmt.Sprintf("\n\texpression: %s", r.Expression)
+	}
+
+	return msg
+}
+
+// Passed checks whether all cases of the suite passed.
+func (r Report) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the results of the failed cases.
+func (r Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if !result.Passed() {
+			failures = append(failures, result)
+		}
+	}
+
+	return failures
+}
+
+// String returns a description of every result, followed by a summary.
+func (r Report) String() string {
+	var sb strings.Builder
+	for _, result := range r.Results {
+		sb.WriteString(result.String())
+		sb.WriteByte('\n')
+	}
+
+	fmt.Fprintf(
+		&sb,
+		"%d passed, %d failed\n",
+		len(r.Results)-len(r.Failures()),
+		len(r.Failures()),
+	)
+
+	return sb.String()
+}
+
+// enforced returns a copy of the given configuration in which the global mode
+// and the mode of every resource that sets one are enforcing. Resources keep
+// their modes set, so that the same resources are registered.
+func enforced(config *authz.AuthzConfig) *authz.AuthzConfig {
+	copied := *config
+	copied.EnforcementMode = authz.EnforcementModeEnforcing
+	copied.Resources = enforcedResources(config.Resources)
+
+	return &copied
+}
+
+func enforcedResources(resources []authz.Resource) []authz.Resource {
+	if resources == nil {
+		return nil
+	}
+
+	copied := make([]authz.Resource, len(resources))
+	for i, resource := range resources {
+		if resource.EnforcementMode != nil {
+			mode := authz.EnforcementModeEnforcing
+			resource.EnforcementMode = &mode
+		}
+
+		resource.Children = enforcedResources(resource.Children)
+		copied[i] = resource
+	}
+
+	return copied
+}
@@ -0,0 +1,165 @@
+// Package authztest provides a framework to unit test authorization policies
+// against suites of cases declared in YAML.
+package authztest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/real-evolution/recloak"
+)
+
+// Expectation is an enum that represents the expected outcome of a case. Its
+// zero value is invalid, so that a case without an expectation is rejected.
+type Expectation int
+
+const (
+	// ExpectAllow is the expectation that the request is allowed.
+	ExpectAllow Expectation = iota + 1
+
+	// ExpectDeny is the expectation that the request is denied.
+	ExpectDeny
+)
+
+// Suite is a struct that holds a named set of policy test cases.
+type Suite struct {
+	// The name of the suite.
+	Name string `yaml:"name,omitempty"`
+
+	// The cases of the suite.
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is a struct that describes a request to a path and its expected
+// outcome.
+type Case struct {
+	// The name of the case.
+	Name string `yaml:"name"`
+
+	// The path of the requested resource.
+	Path string `yaml:"path"`
+
+	// The claims of the caller.
+	Claims CaseClaims `yaml:"claims,omitempty"`
+
+	// The request payload, available as `Request` in policy expressions.
+	Request any `yaml:"request,omitempty"`
+
+	// The expected outcome.
+	Expect Expectation `yaml:"expect"`
+}
+
+// CaseClaims is a struct that describes the claims of the caller of a case.
+type CaseClaims struct {
+	// The subject of the token.
+	Subject string `yaml:"sub,omitempty"`
+
+	// The preferred username of the caller.
+	Username string `yaml:"username,omitempty"`
+
+	// The realm roles of the caller.
+	RealmRoles []string `yaml:"realmRoles,omitempty"`
+
+	// The roles of the caller in the client of the configuration.
+	ClientRoles []string `yaml:"clientRoles,omitempty"`
+
+	// Any other claims of the token, e.g. `scope` or custom claims.
+	Custom map[string]any `yaml:"custom,omitempty"`
+}
+
+// LoadSuite loads a test suite from the YAML file at the given path.
+func LoadSuite(path string) (*Suite, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSuite(content)
+}
+
+// ParseSuite parses a test suite from the given YAML content.
+func ParseSuite(content []byte) (*Suite, error) {
+	var suite Suite
+	if err := yaml.Unmarshal(content, &suite); err != nil {
+		return nil, err
+	}
+
+	for i, c := range suite.Cases {
+		if c.Name == "" {
+			return nil, fmt.Errorf("name of case #%d is empty", i+1)
+		}
+
+		if c.Path == "" {
+			return nil, fmt.Errorf("path of case `%s` is empty", c.Name)
+		}
+
+		if c.Expect != ExpectAllow && c.Expect != ExpectDeny {
+			return nil, fmt.Errorf("expectation of case `%s` is missing", c.Name)
+		}
+	}
+
+	return &suite, nil
+}
+
+// Claims returns the claims of a token issued for the given client.
+func (c CaseClaims) Claims(clientID string) *recloak.Claims {
+	claims := &recloak.Claims{
+		PreferredUsername: c.Username,
+		RealmAcess:        recloak.RolesClaim{Roles: c.RealmRoles},
+		ResourceAcess: map[string]recloak.RolesClaim{
+			clientID: {Roles: c.ClientRoles},
+		},
+		Extra: make(map[string]any, len(c.Custom)+2),
+	}
+	claims.Subject = c.Subject
+
+	for name, value := range c.Custom {
+		claims.Extra[name] = value
+	}
+
+	if c.Subject != "" {
+		claims.Extra["sub"] = c.Subject
+	}
+
+	if c.Username != "" {
+		claims.Extra["preferred_username"] = c.Username
+	}
+
+	return claims
+}
+
+// parseExpectation parses an expectation from a string.
+func parseExpectation(expectStr string) (Expectation, error) {
+	switch strings.ToLower(expectStr) {
+	case "allow":
+		return ExpectAllow, nil
+
+	case "deny":
+		return ExpectDeny, nil
+
+	default:
+		return 0, fmt.Errorf("invalid expectation: %s", expectStr)
+	}
+}
+
+func (e *Expectation) UnmarshalYAML(value *yaml.Node) (err error) {
+	*e, err = parseExpectation(value.Value)
+
+	return
+}
+
+func (e Expectation) String() string {
+	switch e {
+	case ExpectAllow:
+		return "allow"
+
+	case ExpectDeny:
+		return "deny"
+
+	default:
+		return "unknown"
+	}
+}
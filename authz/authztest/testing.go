@@ -0,0 +1,36 @@
+package authztest
+
+import (
+	"testing"
+)
+
+// RunSuite loads the recloak configuration file and the test suite file at
+// the given paths, and runs every case of the suite as a subtest of `t`.
+func RunSuite(t *testing.T, configPath string, suitePath string) {
+	t.Helper()
+
+	runner, err := LoadRunner(configPath)
+	if err != nil {
+		t.Fatalf("could not load config `%s`: %v", configPath, err)
+	}
+
+	suite, err := LoadSuite(suitePath)
+	if err != nil {
+		t.Fatalf("could not load suite `%s`: %v", suitePath, err)
+	}
+
+	RunCases(t, runner, suite)
+}
+
+// RunCases runs every case of the given suite as a subtest of `t`.
+func RunCases(t *testing.T, runner *Runner, suite *Suite) {
+	t.Helper()
+
+	for _, c := range suite.Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if result := runner.RunCase(c); !result.Passed() {
+				t.Error(result.String())
+			}
+		})
+	}
+}
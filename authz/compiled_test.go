@@ -44,10 +44,10 @@ func TestPolicyCompiler(t *testing.T) {
 	require.Equal(t, expectedExpr, compiledPol.source)
 	require.NotNil(t, compiledPol.program)
 
-	result := compiledPol.Evaluate(AuthzEnv{nil, nil, testRequest})
+	result := compiledPol.Evaluate(AuthzEnv{Request: testRequest})
 	require.NoError(t, result)
 
 	testRequest.Foo = "not foo"
-	result = compiledPol.Evaluate(AuthzEnv{nil, nil, testRequest})
+	result = compiledPol.Evaluate(AuthzEnv{Request: testRequest})
 	require.Error(t, result)
 }
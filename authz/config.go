@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/real-evolution/recloak"
 )
 
 type (
@@ -15,6 +17,20 @@ type (
 	// IntrospectionMode is an enum that indicates whether to introspect
 	// user token before evaluating policies.
 	IntrospectionMode int
+
+	// StreamDirection is an enum that indicates which messages of a stream
+	// are authorized individually. The opening of a stream is always
+	// authorized first, with a nil request, so policies of streams must
+	// allow a nil request to let them be opened.
+	StreamDirection int
+
+	// DecisionSource is an enum that indicates where authorization decisions
+	// are made.
+	DecisionSource int
+
+	// RemoteResponseMode is an enum that indicates how keycloak reports
+	// remote authorization decisions.
+	RemoteResponseMode int
 )
 
 const (
@@ -30,6 +46,12 @@ const (
 	// evaluation to succeed regardless of whether a resource has a policy
 	// associated with it.
 	EnforcementModeDisabled
+
+	// EnforcementModeShadow is the enforcement mode that causes the
+	// evaluation to be performed as in enforcing mode, but to succeed anyway,
+	// reporting what would have been denied. It allows rolling out policies
+	// before enforcing them.
+	EnforcementModeShadow
 )
 
 const (
@@ -41,6 +63,57 @@ const (
 	// IntrospectionModeAlways is the introspection mode that causes the
 	// evaluation to always introspect user token before evaluating policies.
 	IntrospectionModeAlways
+
+	// IntrospectionModePeriodic is the introspection mode that causes the
+	// evaluation to introspect user token before evaluating policies, reusing
+	// the cached result until it expires.
+	IntrospectionModePeriodic
+)
+
+const (
+	// StreamDirectionNone is the stream direction that causes only the
+	// opening of a stream to be authorized, without its messages.
+	StreamDirectionNone StreamDirection = iota
+
+	// StreamDirectionRecv is the stream direction that causes every message
+	// received from the client to be authorized.
+	StreamDirectionRecv
+
+	// StreamDirectionSend is the stream direction that causes every message
+	// sent to the client to be authorized.
+	StreamDirectionSend
+
+	// StreamDirectionBoth is the stream direction that causes every message
+	// received from or sent to the client to be authorized.
+	StreamDirectionBoth
+)
+
+const (
+	// DecisionSourceLocal is the decision source that causes decisions to be
+	// made by evaluating the local policies only.
+	DecisionSourceLocal DecisionSource = iota
+
+	// DecisionSourceRemote is the decision source that causes decisions to be
+	// made by keycloak authorization services only, based on the keycloak
+	// permissions of resources.
+	DecisionSourceRemote
+
+	// DecisionSourceBoth is the decision source that causes requests to be
+	// allowed only if both the local policies and keycloak allow them.
+	// Resources that are not mapped to keycloak permissions are decided by
+	// their local policies only.
+	DecisionSourceBoth
+)
+
+const (
+	// RemoteResponseModeDecision is the remote response mode that causes
+	// keycloak to respond with whether all requested permissions are granted.
+	RemoteResponseModeDecision RemoteResponseMode = iota
+
+	// RemoteResponseModePermissions is the remote response mode that causes
+	// keycloak to respond with the granted permissions, which are checked
+	// against the requested ones.
+	RemoteResponseModePermissions
 )
 
 // AuthzConfig is a struct that holds the authorization configuration.
@@ -54,17 +127,38 @@ type AuthzConfig struct {
 	// Whether to introspect user token before evaluating policies.
 	IntrospectionMode IntrospectionMode `yaml:"introspection"`
 
+	// The cache of introspection results, used in periodic introspection
+	// mode.
+	IntrospectionCache IntrospectionCacheOptions `yaml:"introspectionCache,omitempty"`
+
+	// Where authorization decisions are made.
+	DecisionSource DecisionSource `yaml:"decisionSource,omitempty"`
+
+	// How keycloak reports remote authorization decisions.
+	RemoteResponseMode RemoteResponseMode `yaml:"remoteResponseMode,omitempty"`
+
 	// Whether to print debug information.
 	Debug bool `yaml:"debug"`
 
 	// The ID of the client.
 	ClientID string `yaml:"clientID"`
 
+	// The validation options of the access tokens of requests, which default
+	// to the ones of the client.
+	Validation *recloak.ValidationOptions `yaml:"validation,omitempty"`
+
 	// Authorization policies.
 	Policies []Policy `yaml:"policies,flow"`
 
 	// Resources.
 	Resources []Resource `yaml:"resources,flow"`
+
+	// The client roles needed by the policies.
+	Roles []ClientRole `yaml:"roles,omitempty"`
+
+	// The path of the file that the configuration was loaded from, if any,
+	// used to locate errors.
+	File string `yaml:"-"`
 }
 
 // parseEnforcementMode parses enforcement mode from a string.
@@ -79,6 +173,9 @@ func parseEnforcementMode(modeStr string) (EnforcementMode, error) {
 	case "disabled":
 		return EnforcementModeDisabled, nil
 
+	case "shadow":
+		return EnforcementModeShadow, nil
+
 	default:
 		return EnforcementModeEnforcing, fmt.Errorf(
 			"invalid enforcement mode: %s",
@@ -96,6 +193,9 @@ func parseIntrospectionMode(modeStr string) (IntrospectionMode, error) {
 	case "always":
 		return IntrospectionModeAlways, nil
 
+	case "periodic":
+		return IntrospectionModePeriodic, nil
+
 	default:
 		return IntrospectionModeDisabled, fmt.Errorf(
 			"invalid introspection mode: %s",
@@ -104,6 +204,66 @@ func parseIntrospectionMode(modeStr string) (IntrospectionMode, error) {
 	}
 }
 
+// parseStreamDirection parses stream direction from a string.
+func parseStreamDirection(directionStr string) (StreamDirection, error) {
+	switch strings.ToLower(directionStr) {
+	case "none":
+		return StreamDirectionNone, nil
+
+	case "recv":
+		return StreamDirectionRecv, nil
+
+	case "send":
+		return StreamDirectionSend, nil
+
+	case "both":
+		return StreamDirectionBoth, nil
+
+	default:
+		return StreamDirectionNone, fmt.Errorf(
+			"invalid stream direction: %s",
+			directionStr,
+		)
+	}
+}
+
+// parseDecisionSource parses decision source from a string.
+func parseDecisionSource(sourceStr string) (DecisionSource, error) {
+	switch strings.ToLower(sourceStr) {
+	case "local":
+		return DecisionSourceLocal, nil
+
+	case "remote":
+		return DecisionSourceRemote, nil
+
+	case "both":
+		return DecisionSourceBoth, nil
+
+	default:
+		return DecisionSourceLocal, fmt.Errorf(
+			"invalid decision source: %s",
+			sourceStr,
+		)
+	}
+}
+
+// parseRemoteResponseMode parses remote response mode from a string.
+func parseRemoteResponseMode(modeStr string) (RemoteResponseMode, error) {
+	switch strings.ToLower(modeStr) {
+	case "decision":
+		return RemoteResponseModeDecision, nil
+
+	case "permissions":
+		return RemoteResponseModePermissions, nil
+
+	default:
+		return RemoteResponseModeDecision, fmt.Errorf(
+			"invalid remote response mode: %s",
+			modeStr,
+		)
+	}
+}
+
 func (s *EnforcementMode) UnmarshalYAML(value *yaml.Node) (err error) {
 	*s, err = parseEnforcementMode(value.Value)
 
@@ -116,6 +276,24 @@ func (s *IntrospectionMode) UnmarshalYAML(value *yaml.Node) (err error) {
 	return
 }
 
+func (s *StreamDirection) UnmarshalYAML(value *yaml.Node) (err error) {
+	*s, err = parseStreamDirection(value.Value)
+
+	return
+}
+
+func (s *DecisionSource) UnmarshalYAML(value *yaml.Node) (err error) {
+	*s, err = parseDecisionSource(value.Value)
+
+	return
+}
+
+func (s *RemoteResponseMode) UnmarshalYAML(value *yaml.Node) (err error) {
+	*s, err = parseRemoteResponseMode(value.Value)
+
+	return
+}
+
 func (s EnforcementMode) String() string {
 	switch s {
 	case EnforcementModeEnforcing:
@@ -127,6 +305,9 @@ func (s EnforcementMode) String() string {
 	case EnforcementModeDisabled:
 		return "disabled"
 
+	case EnforcementModeShadow:
+		return "shadow"
+
 	default:
 		return "unknown"
 	}
@@ -140,7 +321,68 @@ func (s IntrospectionMode) String() string {
 	case IntrospectionModeAlways:
 		return "always"
 
+	case IntrospectionModePeriodic:
+		return "periodic"
+
+	default:
+		return "unknown"
+	}
+}
+
+func (s StreamDirection) String() string {
+	switch s {
+	case StreamDirectionNone:
+		return "none"
+
+	case StreamDirectionRecv:
+		return "recv"
+
+	case StreamDirectionSend:
+		return "send"
+
+	case StreamDirectionBoth:
+		return "both"
+
+	default:
+		return "unknown"
+	}
+}
+
+func (s DecisionSource) String() string {
+	switch s {
+	case DecisionSourceLocal:
+		return "local"
+
+	case DecisionSourceRemote:
+		return "remote"
+
+	case DecisionSourceBoth:
+		return "both"
+
 	default:
 		return "unknown"
 	}
 }
+
+func (s RemoteResponseMode) String() string {
+	switch s {
+	case RemoteResponseModeDecision:
+		return "decision"
+
+	case RemoteResponseModePermissions:
+		return "permissions"
+
+	default:
+		return "unknown"
+	}
+}
+
+// Recv checks whether received messages are authorized.
+func (s StreamDirection) Recv() bool {
+	return s == StreamDirectionRecv || s == StreamDirectionBoth
+}
+
+// Send checks whether sent messages are authorized.
+func (s StreamDirection) Send() bool {
+	return s == StreamDirectionSend || s == StreamDirectionBoth
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
+
+	"github.com/real-evolution/recloak"
 )
 
 func TestDecodeEnforcementModeFromYAML(t *testing.T) {
@@ -34,6 +36,11 @@ func TestDecodeEnforcementModeFromYAML(t *testing.T) {
 			expected: EnforcementModeDisabled,
 			hasError: false,
 		},
+		{
+			yaml:     "shadow",
+			expected: EnforcementModeShadow,
+			hasError: false,
+		},
 		{
 			yaml:     "permissivee",
 			expected: EnforcementModeEnforcing,
@@ -55,6 +62,59 @@ func TestDecodeEnforcementModeFromYAML(t *testing.T) {
 	}
 }
 
+func TestDecodeStreamDirectionFromYAML(t *testing.T) {
+	testData := []struct {
+		yaml     string
+		expected StreamDirection
+		hasError bool
+	}{
+		{yaml: "none", expected: StreamDirectionNone},
+		{yaml: "recv", expected: StreamDirectionRecv},
+		{yaml: "send", expected: StreamDirectionSend},
+		{yaml: "BOTH", expected: StreamDirectionBoth},
+		{yaml: "in", expected: StreamDirectionNone, hasError: true},
+	}
+
+	for _, data := range testData {
+		var actual StreamDirection
+
+		err := yaml.Unmarshal([]byte(data.yaml), &actual)
+		require.Equal(t, data.expected, actual)
+
+		if data.hasError {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestDecodeDecisionSourceFromYAML(t *testing.T) {
+	testData := []struct {
+		yaml     string
+		expected DecisionSource
+		hasError bool
+	}{
+		{yaml: "local", expected: DecisionSourceLocal},
+		{yaml: "remote", expected: DecisionSourceRemote},
+		{yaml: "both", expected: DecisionSourceBoth},
+		{yaml: "keycloak", expected: DecisionSourceLocal, hasError: true},
+	}
+
+	for _, data := range testData {
+		var actual DecisionSource
+
+		err := yaml.Unmarshal([]byte(data.yaml), &actual)
+		require.Equal(t, data.expected, actual)
+
+		if data.hasError {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+}
+
 func TestDecodeConfigFromYAML(t *testing.T) {
 	const expectedPathSeparator = "/"
 	const expectedEnforcementMode = EnforcementModePermissive
@@ -103,6 +163,9 @@ resources:
 				Name:        expectedPolicyName,
 				Description: expectedPolicyDescription,
 				Expression:  expectedPolicyExpression,
+
+				Position:           Position{Line: 7, Column: 5},
+				ExpressionPosition: Position{Line: 9, Column: 17},
 			},
 		},
 		Resources: []Resource{
@@ -110,8 +173,10 @@ resources:
 				Name:        expectedResourceName,
 				DisplayName: expectedResourceDisplayName,
 				Policy: &PolicySpec{
-					Ref: expectedResourcePolicyRef,
+					Ref:      expectedResourcePolicyRef,
+					Position: Position{Line: 13, Column: 13},
 				},
+				Position: Position{Line: 11, Column: 5},
 			},
 		},
 	}
@@ -121,3 +186,18 @@ resources:
 	require.NoError(t, err)
 	require.Equal(t, expected, actual)
 }
+
+func TestDecodeValidationFromYAML(t *testing.T) {
+	const configYAML = `
+validation:
+  skipAudienceCheck: true
+  audiences: [api]
+`
+
+	var actual AuthzConfig
+	require.NoError(t, yaml.Unmarshal([]byte(configYAML), &actual))
+	require.Equal(t, &recloak.ValidationOptions{
+		SkipAudienceCheck: true,
+		Audiences:         []string{"api"},
+	}, actual.Validation)
+}
@@ -0,0 +1,150 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Clause is a policy that is part of the effective expression of a resource,
+// either declared on the resource itself or inherited from its ancestors.
+type Clause struct {
+	// The name of the policy, empty for unnamed in-place policies.
+	Policy string
+
+	// The path of the resource on which the policy is declared.
+	Resource string
+
+	// The preprocessed expression of the policy.
+	Expression string
+}
+
+// Decision is the result of an authorization request, explaining how it was
+// reached.
+type Decision struct {
+	// The requested path.
+	Path string
+
+	// The path of the matched resource, which may be a pattern. It is empty
+	// if no resource matched the requested path.
+	Resource string
+
+	// Parameters captured from the requested path.
+	Params map[string]string
+
+	// The effective expression of the matched resource, composed of all of
+	// its clauses.
+	Expression string
+
+	// The clauses of the effective expression, from the outermost resource
+	// to the matched one.
+	Clauses []Clause
+
+	// The keycloak permissions, as `resource#scope`, that were requested for a
+	// remote decision.
+	Permissions []string
+
+	// The first clause that evaluated to false, if the request was denied by
+	// a policy.
+	FailedClause *Clause
+
+	// The enforcement mode in effect.
+	Mode EnforcementMode
+
+	// Whether the request is allowed.
+	Allowed bool
+
+	// Whether the request is allowed only because of the shadow mode, and
+	// would have been denied otherwise.
+	Shadowed bool
+
+	// The reason the request was denied, or would have been denied in shadow
+	// mode. It is nil otherwise.
+	Err error
+
+	// When the decision was made.
+	Timestamp time.Time
+
+	// How long the evaluation took.
+	Duration time.Duration
+}
+
+// Policies returns the names of the policies contributing to the decision.
+func (d *Decision) Policies() []string {
+	names := make([]string, 0, len(d.Clauses))
+	for _, clause := range d.Clauses {
+		if clause.Policy != "" {
+			names = append(names, clause.Policy)
+		}
+	}
+
+	return names
+}
+
+// String returns a human-readable explanation of the decision.
+func (d *Decision) String() string {
+	var sb strings.Builder
+
+	if d.Shadowed {
+		sb.WriteString("would have denied")
+	} else if d.Allowed {
+		sb.WriteString("allowed")
+	} else {
+		sb.WriteString("denied")
+	}
+
+	fmt.Fprintf(&sb, " access to `%s` (mode: %s", d.Path, d.Mode)
+
+	if d.Resource != "" {
+		fmt.Fprintf(&sb, ", resource: `%s`", d.Resource)
+	}
+
+	if d.FailedClause != nil {
+		clause := d.FailedClause
+
+		sb.WriteString(", failed: ")
+		if clause.Policy != "" {
+			fmt.Fprintf(&sb, "policy `%s` ", clause.Policy)
+		}
+		fmt.Fprintf(&sb, "of `%s`: %s", clause.Resource, clause.Expression)
+	}
+
+	if d.Err != nil && d.FailedClause == nil {
+		fmt.Fprintf(&sb, ", error: %s", d.Err)
+	}
+
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+// MarshalZerologObject implements `zerolog.LogObjectMarshaler`.
+func (d *Decision) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("path", d.Path).
+		Bool("allowed", d.Allowed).
+		Bool("shadowed", d.Shadowed).
+		Stringer("mode", d.Mode).
+		Dur("duration", d.Duration)
+
+	if d.Resource != "" {
+		e.Str("resource", d.Resource).
+			Str("expression", d.Expression).
+			Strs("policies", d.Policies())
+	}
+
+	if len(d.Permissions) > 0 {
+		e.Strs("permissions", d.Permissions)
+	}
+
+	if d.FailedClause != nil {
+		e.Str("failedPolicy", d.FailedClause.Policy).
+			Str("failedResource", d.FailedClause.Resource).
+			Str("failedExpression", d.FailedClause.Expression)
+	}
+
+	if d.Err != nil {
+		e.AnErr("reason", d.Err)
+	}
+}
@@ -0,0 +1,131 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngineDecide(t *testing.T) {
+	type testRequest struct {
+		Name string
+	}
+
+	config := AuthzConfig{
+		PathSeparator:   ".",
+		EnforcementMode: EnforcementModeEnforcing,
+		Policies: []Policy{
+			{Name: "authenticated", Expression: "Claims != nil"},
+			{Name: "is-foo", Expression: `Request.Name == "foo"`},
+		},
+		Resources: []Resource{
+			{
+				Name:   "api",
+				Policy: &PolicySpec{Ref: "authenticated"},
+				Children: []Resource{
+					{
+						Name:   "foo",
+						Policy: &PolicySpec{Ref: "is-foo"},
+						Children: []Resource{
+							{
+								Name: "{id}",
+								Policy: &PolicySpec{
+									InPlace: &Policy{Expression: `Params.id != "0"`},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	t.Run("failed outer clause", func(t *testing.T) {
+		decision := engine.Decide("api.foo.1", nil, testRequest{"foo"})
+
+		require.False(t, decision.Allowed)
+		require.Equal(t, "api.foo.{id}", decision.Resource)
+		require.Equal(t, map[string]string{"id": "1"}, decision.Params)
+		require.Equal(t, []string{"authenticated", "is-foo"}, decision.Policies())
+		require.Len(t, decision.Clauses, 3)
+		require.Equal(t, EnforcementModeEnforcing, decision.Mode)
+		require.False(t, decision.Timestamp.IsZero())
+
+		require.NotNil(t, decision.FailedClause)
+		require.Equal(t, "authenticated", decision.FailedClause.Policy)
+		require.Equal(t, "api", decision.FailedClause.Resource)
+		require.ErrorIs(t, decision.Err, ErrUnauthorized)
+	})
+
+	t.Run("failed inherited clause", func(t *testing.T) {
+		decision := engine.Decide("api.foo", nil, testRequest{"bar"})
+
+		require.False(t, decision.Allowed)
+		require.Equal(t, "api.foo", decision.Resource)
+		require.Equal(
+			t,
+			"((Claims != nil)) && (Request.Name == \"foo\")",
+			decision.Expression,
+		)
+		require.Equal(t, "authenticated", decision.FailedClause.Policy)
+	})
+
+	t.Run("no policy for path", func(t *testing.T) {
+		decision := engine.Decide("unknown", nil, nil)
+
+		require.False(t, decision.Allowed)
+		require.Empty(t, decision.Resource)
+		require.Nil(t, decision.FailedClause)
+		require.ErrorIs(t, decision.Err, ErrorNoPolicyForPath)
+		require.Contains(t, decision.String(), "no policy for path")
+	})
+}
+
+func TestEngineDecideFailedClause(t *testing.T) {
+	config := AuthzConfig{
+		PathSeparator:   ".",
+		EnforcementMode: EnforcementModeEnforcing,
+		Resources: []Resource{
+			{
+				Name:   "api",
+				Policy: &PolicySpec{InPlace: &Policy{Expression: "true"}},
+				Children: []Resource{
+					{
+						Name: "admin",
+						Policy: &PolicySpec{
+							InPlace: &Policy{
+								Name:       "admins-only",
+								Expression: `Request == "admin"`,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	decision := engine.Decide("api.admin", nil, "user")
+	require.False(t, decision.Allowed)
+	require.Equal(t, &Clause{
+		Policy:     "admins-only",
+		Resource:   "api.admin",
+		Expression: `Request == "admin"`,
+	}, decision.FailedClause)
+	require.Equal(
+		t,
+		"denied access to `api.admin` (mode: enforcing, resource: `api.admin`, "+
+			"failed: policy `admins-only` of `api.admin`: Request == \"admin\")",
+		decision.String(),
+	)
+
+	decision = engine.Decide("api.admin", nil, "admin")
+	require.True(t, decision.Allowed)
+	require.Nil(t, decision.FailedClause)
+	require.NoError(t, decision.Err)
+}
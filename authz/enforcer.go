@@ -2,21 +2,34 @@ package authz
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Nerzal/gocloak/v13"
+	"github.com/rs/zerolog/log"
 
 	"github.com/real-evolution/recloak"
 )
 
+// ErrUnavailable is returned when a decision could not be made because keycloak
+// or the revocation store could not be reached, as opposed to the token being
+// invalid.
+var ErrUnavailable = errors.New("authorization is unavailable")
+
 type Enforcer struct {
-	client *recloak.ReCloak
-	engine *Engine
-	config *AuthzConfig
+	client    *recloak.ReCloak
+	engine    *ReloadableEngine
+	auditSink AuditSink
+
+	revocations RevocationStore
+
+	introspectionCache *IntrospectionCache
 }
 
 // NewEnforcer creates a new authorization enforcer.
 func NewEnforcer(client *recloak.ReCloak, config *AuthzConfig) (*Enforcer, error) {
-	engine, err := NewEngine(config)
+	engine, err := NewReloadableEngine(config)
 	if err != nil {
 		return nil, err
 	}
@@ -24,7 +37,8 @@ func NewEnforcer(client *recloak.ReCloak, config *AuthzConfig) (*Enforcer, error
 	return &Enforcer{
 		client: client,
 		engine: engine,
-		config: config,
+
+		introspectionCache: NewIntrospectionCache(config.IntrospectionCache),
 	}, nil
 }
 
@@ -35,50 +49,185 @@ func (e *Enforcer) Authorize(
 	path string,
 	request any,
 ) (recloak.Token, error) {
-	if e.engine.config.EnforcementMode == EnforcementModeDisabled {
-		return recloak.Token{}, nil
+	token, decision := e.Decide(ctx, accessToken, path, request)
+	if !decision.Allowed {
+		return recloak.Token{}, decision.Err
 	}
 
-	if e.config.IntrospectionMode == IntrospectionModeAlways {
-		result, err := e.introspectToken(ctx, accessToken)
-		if err != nil {
-			return recloak.Token{}, err
-		}
+	return token, nil
+}
 
-		if result.Active == nil || !*result.Active {
-			return recloak.Token{}, ErrUnauthorized
+// Decide authenticates the given access token and evaluates a policy for a
+// path with its claims and the given request, returning the decoded token and
+// a decision explaining the result.
+//
+// The decision is recorded to the audit sink, if any.
+func (e *Enforcer) Decide(
+	ctx context.Context,
+	accessToken string,
+	path string,
+	request any,
+) (recloak.Token, Decision) {
+	token, decision := e.decide(ctx, accessToken, path, request)
+	e.report(ctx, token.Claims, &decision)
+
+	return token, decision
+}
+
+// DecideMessage evaluates a policy for a path with the claims of a token that
+// was already authenticated, e.g. when its stream was opened, and a message of
+// the stream as the request. The token is not authenticated again, but the
+// decision is still made from the configured decision source.
+//
+// The decision is recorded to the audit sink, if any.
+func (e *Enforcer) DecideMessage(
+	ctx context.Context,
+	accessToken string,
+	token recloak.Token,
+	path string,
+	message any,
+) Decision {
+	engine := e.engine.Engine()
+
+	var decision Decision
+	switch mode := engine.EnforcementModeOf(path); {
+	case mode == EnforcementModeDisabled:
+		decision = engine.Decide(path, nil, message)
+
+	case token.Claims == nil:
+		// the stream was opened while enforcement was disabled
+		decision = Decision{
+			Path:      path,
+			Mode:      mode,
+			Err:       recloak.ErrInvalidToken,
+			Timestamp: time.Now(),
 		}
+
+	default:
+		decision = e.decideFrom(ctx, engine, accessToken, token.Claims, path, message)
 	}
 
-	claims := &recloak.Claims{}
-	decodedToken, err := e.client.Client().DecodeAccessTokenCustomClaims(
-		ctx,
-		accessToken,
-		e.client.Config().Realm,
-		claims,
-	)
-	if err != nil {
-		return recloak.Token{}, err
+	e.report(ctx, token.Claims, &decision)
+
+	return decision
+}
+
+// DenyUnauthenticated records the denial of a request to a path that was
+// rejected before any decision could be made, because it carried no access
+// token, a malformed one, or one that could not be authenticated, and returns
+// a decision explaining it.
+//
+// The decision is recorded to the audit sink, if any.
+func (e *Enforcer) DenyUnauthenticated(
+	ctx context.Context,
+	path string,
+	err error,
+) Decision {
+	decision := Decision{
+		Path:      path,
+		Mode:      e.engine.Engine().EnforcementModeOf(path),
+		Err:       err,
+		Timestamp: time.Now(),
 	}
+	e.report(ctx, nil, &decision)
+
+	return decision
+}
+
+// Authenticate decodes and verifies the given access token, introspecting it
+// if configured to, without evaluating any policy.
+func (e *Enforcer) Authenticate(
+	ctx context.Context,
+	accessToken string,
+) (recloak.Token, error) {
+	return e.authenticate(ctx, e.engine.Config(), accessToken)
+}
+
+// SetAuditSink sets the sink that records every decision of the enforcer.
+func (e *Enforcer) SetAuditSink(sink AuditSink) {
+	e.auditSink = sink
+}
 
-	if !decodedToken.Valid {
-		return recloak.Token{}, ErrUnauthorized
+func (e *Enforcer) decide(
+	ctx context.Context,
+	accessToken string,
+	path string,
+	request any,
+) (recloak.Token, Decision) {
+	engine := e.engine.Engine()
+
+	mode := engine.EnforcementModeOf(path)
+	if mode == EnforcementModeDisabled {
+		return recloak.Token{}, engine.Decide(path, nil, request)
 	}
 
-	err = e.engine.Authorize(path, claims, request)
+	token, err := e.authenticate(ctx, engine.config, accessToken)
 	if err != nil {
-		return recloak.Token{}, err
+		return recloak.Token{}, Decision{
+			Path:      path,
+			Mode:      mode,
+			Err:       err,
+			Timestamp: time.Now(),
+		}
 	}
 
-	return recloak.Token{
-		Token:  decodedToken,
-		Claims: claims,
-	}, nil
+	return token, e.decideFrom(ctx, engine, accessToken, token.Claims, path, request)
+}
+
+func (e *Enforcer) report(
+	ctx context.Context,
+	claims *recloak.Claims,
+	decision *Decision,
+) {
+	if decision.Shadowed {
+		log.Warn().
+			Object("decision", decision).
+			Msg("access to resource would have been denied")
+	}
+
+	if e.auditSink != nil {
+		record := NewAuditRecord(ctx, claims, decision)
+		if err := e.auditSink.Record(ctx, record); err != nil {
+			log.Warn().
+				Err(err).
+				Str("path", decision.Path).
+				Msg("could not audit decision")
+		}
+	}
+}
+
+// SetRevocationStore sets the store of revoked sessions and subjects, against
+// which authenticated tokens are checked.
+func (e *Enforcer) SetRevocationStore(store RevocationStore) {
+	e.revocations = store
 }
 
-// SetEnforcementMode sets the enforcement mode.
+// SetEnforcementMode sets the global enforcement mode, until the
+// configuration is reloaded.
 func (e *Enforcer) SetEnforcementMode(mode EnforcementMode) {
-	e.config.EnforcementMode = mode
+	e.engine.Engine().SetEnforcementMode(mode)
+}
+
+// Config returns the authorization configuration.
+func (e *Enforcer) Config() *AuthzConfig {
+	return e.engine.Config()
+}
+
+// Engine returns the reloadable authorization engine.
+func (e *Enforcer) Engine() *ReloadableEngine {
+	return e.engine
+}
+
+// Reload compiles the given configuration and swaps it in on success, keeping
+// the current one otherwise.
+func (e *Enforcer) Reload(config *AuthzConfig) error {
+	return e.engine.Reload(config)
+}
+
+// IntrospectionCache returns the cache of introspection results, used in
+// periodic introspection mode. Its options are not changed on reload.
+func (e *Enforcer) IntrospectionCache() *IntrospectionCache {
+	return e.introspectionCache
 }
 
 // Client returns the recloak client
@@ -86,17 +235,102 @@ func (e *Enforcer) Client() *recloak.ReCloak {
 	return e.client
 }
 
+func (e *Enforcer) authenticate(
+	ctx context.Context,
+	config *AuthzConfig,
+	accessToken string,
+) (recloak.Token, error) {
+	if config.IntrospectionMode != IntrospectionModeDisabled {
+		result, err := e.introspectToken(ctx, config, accessToken)
+		if err != nil {
+			return recloak.Token{}, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+
+		if result.Active == nil || !*result.Active {
+			return recloak.Token{}, recloak.ErrInvalidToken
+		}
+	}
+
+	validation := e.client.Config().Validation
+	if config.Validation != nil {
+		validation = *config.Validation
+	}
+
+	token, err := e.client.DecodeAccessTokenWithOptions(ctx, accessToken, validation)
+	if errors.Is(err, recloak.ErrKeysUnavailable) {
+		return recloak.Token{}, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	} else if err != nil {
+		return recloak.Token{}, fmt.Errorf("%w: %w", recloak.ErrInvalidToken, err)
+	}
+
+	if !token.Valid {
+		return recloak.Token{}, recloak.ErrInvalidToken
+	}
+
+	if e.revocations != nil {
+		if err := e.checkRevocation(ctx, token.Claims); err != nil {
+			return recloak.Token{}, err
+		}
+	}
+
+	return token, nil
+}
+
+// checkRevocation checks that neither the session nor the subject of the
+// token were revoked after it was issued.
+func (e *Enforcer) checkRevocation(ctx context.Context, claims *recloak.Claims) error {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	revoked, err := e.revocations.IsRevoked(
+		ctx,
+		claims.SessionID,
+		claims.Subject,
+		issuedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// introspectToken introspects the given token, using the cached result in
+// periodic introspection mode.
 func (e *Enforcer) introspectToken(
 	ctx context.Context,
+	config *AuthzConfig,
 	accessToken string,
 ) (*gocloak.IntroSpectTokenResult, error) {
+	periodic := config.IntrospectionMode == IntrospectionModePeriodic
+	if periodic {
+		if result, ok := e.introspectionCache.Get(accessToken); ok {
+			return result, nil
+		}
+	}
+
 	cfg := e.client.Config()
 
-	return e.client.Client().RetrospectToken(
+	result, err := e.client.Client().RetrospectToken(
 		ctx,
 		accessToken,
 		cfg.ClientID,
 		cfg.ClientSecret,
 		cfg.Realm,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if periodic {
+		e.introspectionCache.Put(accessToken, result)
+	}
+
+	return result, nil
 }
@@ -1,7 +1,13 @@
 package authz
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
@@ -12,75 +18,320 @@ var ErrorNoPolicyForPath = fmt.Errorf("no policy for path")
 
 // Engine is a struct that is used to evaluate authorization policies.
 type Engine struct {
-	config           *AuthzConfig
-	rawPolicies      PolicyMap
-	compiledPolicies map[string]CompiledPolicy
+	config      *AuthzConfig
+	rawPolicies PolicyMap
+
+	// global enforcement mode, which may be changed at runtime
+	mode atomic.Int32
+
+	// resources with literal paths, indexed by path
+	resources map[string]*compiledResource
+
+	// resources with wildcards or parameters, most specific first
+	patterns []*compiledResource
+
+	// compiled clause expressions, shared between resources
+	clausePolicies map[string]CompiledPolicy
+
+	// errors found while building the engine
+	errs ConfigErrors
+}
+
+// compiledResource is a resource path with its compiled policy.
+type compiledResource struct {
+	path           string
+	pattern        pathPattern
+	mode           *EnforcementMode
+	direction      StreamDirection
+	hasPolicy      bool
+	policy         CompiledPolicy
+	clauses        []Clause
+	clausePolicies []CompiledPolicy
+
+	// keycloak permissions, as `resource#scope`, for remote decisions
+	permission  *KeycloakPermission
+	permissions []string
+
+	displayName string
 }
 
 // NewEngine creates a new authorization engine.
+//
+// All errors of the configuration are collected, and returned as
+// `ConfigErrors`.
 func NewEngine(config *AuthzConfig) (*Engine, error) {
-	rawPolicies, err := NewPolicyMap(config)
-	if err != nil {
-		return nil, err
-	}
+	rawPolicies, errs := newPolicyMap(config)
 
 	engine := &Engine{
-		config:           config,
-		rawPolicies:      rawPolicies,
-		compiledPolicies: make(map[string]CompiledPolicy),
+		config:      config,
+		rawPolicies: rawPolicies,
+		resources:   make(map[string]*compiledResource),
+
+		clausePolicies: make(map[string]CompiledPolicy),
+		errs:           errs,
 	}
+	engine.mode.Store(int32(config.EnforcementMode))
+
+	if err := validateRoles(config.Roles); err != nil {
+		engine.errs = append(engine.errs, &ConfigError{File: config.File, Err: err})
+	}
+
+	engine.fillFromResources()
 
-	if err := engine.fillFromResources(); err != nil {
-		return nil, err
+	if len(engine.errs) > 0 {
+		return nil, engine.errs
 	}
 
+	sort.SliceStable(engine.patterns, func(i, j int) bool {
+		return engine.patterns[i].pattern.moreSpecific(engine.patterns[j].pattern)
+	})
+
 	return engine, nil
 }
 
 // Authorize evaluates a policy for a path, with the given claims and request.
 func (e *Engine) Authorize(path string, claims *recloak.Claims, request any) error {
-	if e.config.EnforcementMode == EnforcementModeDisabled {
+	decision := e.Decide(path, claims, request)
+	if decision.Allowed {
 		return nil
 	}
 
-	if policy, ok := e.compiledPolicies[path]; ok {
+	return decision.Err
+}
+
+// Decide evaluates a policy for a path, with the given claims and request,
+// and returns a decision explaining the result.
+func (e *Engine) Decide(path string, claims *recloak.Claims, request any) Decision {
+	return e.decide(path, func(
+		resource *compiledResource,
+		params map[string]string,
+		decision *Decision,
+	) error {
+		if !resource.hasPolicy {
+			return ErrorNoPolicyForPath
+		}
+
 		env := AuthzEnv{
 			Config:  e.config,
 			Claims:  claims,
 			Request: request,
+			Params:  params,
 		}
 
-		return policy.Evaluate(env)
+		decision.Expression = resource.policy.source
+		decision.Clauses = resource.clauses
+
+		err := resource.policy.Evaluate(env)
+		if errors.Is(err, ErrUnauthorized) {
+			decision.FailedClause = resource.failedClause(env)
+		}
+
+		return err
+	})
+}
+
+// decideRemote makes a decision for a path using the given check of the
+// keycloak permissions of its resource.
+func (e *Engine) decideRemote(
+	path string,
+	check func(permissions []string) error,
+) Decision {
+	return e.decide(path, func(
+		resource *compiledResource,
+		_ map[string]string,
+		decision *Decision,
+	) error {
+		if len(resource.permissions) == 0 {
+			return ErrorNoPolicyForPath
+		}
+
+		decision.Permissions = resource.permissions
+
+		return check(resource.permissions)
+	})
+}
+
+// isMapped checks whether the resource matching a path is mapped to keycloak
+// permissions.
+func (e *Engine) isMapped(path string) bool {
+	resource, _, ok := e.lookup(path)
+
+	return ok && len(resource.permissions) > 0
+}
+
+// decide makes a decision for a path, applying the enforcement mode to the
+// result of the given evaluation of the matched resource.
+func (e *Engine) decide(
+	path string,
+	evaluate func(*compiledResource, map[string]string, *Decision) error,
+) Decision {
+	decision := Decision{
+		Path:      path,
+		Timestamp: time.Now(),
 	}
 
-	if e.config.EnforcementMode == EnforcementModeEnforcing {
-		return ErrorNoPolicyForPath
+	resource, params, ok := e.lookup(path)
+	decision.Mode = e.modeOf(resource)
+
+	if decision.Mode == EnforcementModeDisabled {
+		decision.Allowed = true
+		return decision
 	}
 
-	return nil
+	if ok {
+		decision.Resource = resource.path
+		decision.Params = params
+		decision.Err = evaluate(resource, params, &decision)
+	} else {
+		decision.Err = ErrorNoPolicyForPath
+	}
+
+	if errors.Is(decision.Err, ErrorNoPolicyForPath) &&
+		decision.Mode == EnforcementModePermissive {
+		decision.Err = nil
+	}
+
+	decision.Allowed = decision.Err == nil
+
+	if !decision.Allowed && decision.Mode == EnforcementModeShadow {
+		decision.Allowed = true
+		decision.Shadowed = true
+	}
+
+	decision.Duration = time.Since(decision.Timestamp)
+
+	return decision
+}
+
+// EnforcementMode returns the global enforcement mode.
+func (e *Engine) EnforcementMode() EnforcementMode {
+	return EnforcementMode(e.mode.Load())
 }
 
+// SetEnforcementMode sets the global enforcement mode. It is safe to call
+// while requests are being authorized, and does not affect resources with
+// their own enforcement mode.
 func (e *Engine) SetEnforcementMode(mode EnforcementMode) {
-	e.config.EnforcementMode = mode
+	e.mode.Store(int32(mode))
 }
 
-func (e *Engine) fillFromResources() error {
-	for _, resource := range e.config.Resources {
-		if err := e.addResource(resource, "", PolicyCompiler{}); err != nil {
-			return err
+// EnforcementModeOf returns the enforcement mode in effect for a path.
+func (e *Engine) EnforcementModeOf(path string) EnforcementMode {
+	resource, _, _ := e.lookup(path)
+
+	return e.modeOf(resource)
+}
+
+// StreamDirectionOf returns the messages to authorize individually for a
+// streaming call to a path.
+func (e *Engine) StreamDirectionOf(path string) StreamDirection {
+	if resource, _, ok := e.lookup(path); ok {
+		return resource.direction
+	}
+
+	return StreamDirectionNone
+}
+
+// modeOf returns the enforcement mode of the given resource, which may be nil.
+func (e *Engine) modeOf(resource *compiledResource) EnforcementMode {
+	if resource != nil && resource.mode != nil {
+		return *resource.mode
+	}
+
+	return e.EnforcementMode()
+}
+
+// ProtectedResources returns the resources of the engine that have a policy
+// or a keycloak permission, sorted by path.
+func (e *Engine) ProtectedResources() []ProtectedResource {
+	resources := make([]ProtectedResource, 0, len(e.resources))
+	for _, resource := range e.resources {
+		if !resource.hasPolicy && resource.permission == nil {
+			continue
+		}
+
+		protected := ProtectedResource{
+			Path:        resource.path,
+			DisplayName: resource.displayName,
+			Clauses:     resource.clauses,
+		}
+
+		if resource.hasPolicy {
+			protected.Expression = resource.policy.source
+		}
+
+		if resource.permission != nil {
+			permission := *resource.permission
+			protected.Keycloak = &permission
 		}
+
+		resources = append(resources, protected)
 	}
 
-	return nil
+	slices.SortFunc(resources, func(a, b ProtectedResource) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return resources
 }
 
+// lookup finds the resource of the given path, preferring literal paths over
+// patterns, and patterns over less specific ones.
+func (e *Engine) lookup(path string) (*compiledResource, map[string]string, bool) {
+	if resource, ok := e.resources[path]; ok {
+		return resource, nil, true
+	}
+
+	if len(e.patterns) == 0 {
+		return nil, nil, false
+	}
+
+	segments := splitPath(path, e.config.PathSeparator)
+	for _, resource := range e.patterns {
+		if params, ok := resource.pattern.match(segments); ok {
+			return resource, params, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+func (e *Engine) fillFromResources() {
+	for _, resource := range e.config.Resources {
+		e.addResource(resource, "", inheritedSettings{})
+	}
+}
+
+// addError records an error of the configuration at the given position.
+func (e *Engine) addError(position Position, resource string, err error) {
+	e.errs = append(e.errs, &ConfigError{
+		File:     e.config.File,
+		Position: position,
+		Resource: resource,
+		Err:      err,
+	})
+}
+
+// inheritedSettings holds the settings that a resource inherits from its
+// ancestors.
+type inheritedSettings struct {
+	compiler   PolicyCompiler
+	clauses    []Clause
+	mode       *EnforcementMode
+	direction  StreamDirection
+	permission *KeycloakPermission
+}
+
+// addResource compiles the given resource and its children, recording their
+// errors. Children of resources with invalid paths are skipped.
 func (e *Engine) addResource(
 	resource Resource,
 	currentPath string,
-	compiler PolicyCompiler,
-) error {
+	inherited inheritedSettings,
+) {
 	if resource.Name == "" {
-		return fmt.Errorf("resource name is empty")
+		e.addError(resource.Position, currentPath, fmt.Errorf("resource name is empty"))
+		return
 	}
 
 	if currentPath == "" {
@@ -89,54 +340,212 @@ func (e *Engine) addResource(
 		currentPath = fmt.Sprintf("%s%s%s", currentPath, e.config.PathSeparator, resource.Name)
 	}
 
-	if _, ok := e.compiledPolicies[currentPath]; ok {
-		return fmt.Errorf("duplicate resource name: %s", currentPath)
+	if _, ok := e.resources[currentPath]; ok {
+		e.addError(resource.Position, "", fmt.Errorf("duplicate resource name: %s", currentPath))
+		return
+	}
+
+	pattern, err := parsePathPattern(currentPath, e.config.PathSeparator)
+	if err != nil {
+		e.addError(resource.Position, currentPath, err)
+		return
+	}
+
+	if resource.EnforcementMode != nil {
+		inherited.mode = resource.EnforcementMode
+	}
+
+	if resource.StreamDirection != nil {
+		inherited.direction = *resource.StreamDirection
+	}
+
+	if resource.Keycloak != nil {
+		inherited.permission = resource.Keycloak.inherit(inherited.permission)
+		if inherited.permission.Resource == "" {
+			e.addError(resource.Position, currentPath, fmt.Errorf("keycloak resource is empty"))
+		}
 	}
 
 	if resource.Policy != nil {
-		var policy Policy
-		var ok bool
-
-		if resource.Policy.InPlace != nil {
-			policy = *resource.Policy.InPlace
-		} else if resource.Policy.Ref != "" {
-			if policy, ok = e.rawPolicies.Get(resource.Policy.Ref); !ok {
-				return fmt.Errorf("policy %s not found", resource.Policy.Ref)
+		if policy, ok := e.resolvePolicy(resource.Policy, currentPath); ok {
+			inherited.compiler = inherited.compiler.And(policy.Expression)
+			inherited.clauses = append(slices.Clip(inherited.clauses), Clause{
+				Policy:     policy.Name,
+				Resource:   currentPath,
+				Expression: policy.Expression,
+			})
+		}
+	}
+
+	// resources with their own enforcement mode or stream direction are
+	// registered even without a policy, so that their settings apply
+	if !inherited.compiler.IsEmpty() ||
+		inherited.permission != nil ||
+		resource.EnforcementMode != nil ||
+		resource.StreamDirection != nil {
+		compiled := &compiledResource{
+			path:      currentPath,
+			pattern:   pattern,
+			mode:      inherited.mode,
+			direction: inherited.direction,
+			clauses:   inherited.clauses,
+
+			permission:  inherited.permission,
+			displayName: resource.DisplayName,
+		}
+
+		if inherited.permission != nil {
+			compiled.permissions = inherited.permission.permissions()
+		}
+
+		if !inherited.compiler.IsEmpty() {
+			if e.config.Debug {
+				log.Debug().
+					Str("path", currentPath).
+					Str("expression", inherited.compiler.currentExpr).
+					Msg("adding policy")
 			}
-		} else {
-			return fmt.Errorf("policy specification of resource `%s` is empty", resource.Name)
+
+			compiled.policy, err = inherited.compiler.Compile()
+			if err == nil {
+				compiled.clausePolicies, err = e.compileClauses(inherited.clauses)
+			}
+
+			if err != nil {
+				e.addError(resource.Position, currentPath, err)
+			}
+
+			compiled.hasPolicy = true
+		}
+
+		e.resources[currentPath] = compiled
+		if !pattern.isLiteral() {
+			e.patterns = append(e.patterns, compiled)
 		}
+	}
+
+	for _, child := range resource.Children {
+		e.addResource(child, currentPath, inherited)
+	}
+}
 
-		if policy.Expression == "" {
-			return fmt.Errorf("policy `%s` expression is empty", policy.Name)
+// resolvePolicy returns the preprocessed policy of the given spec of the
+// resource at the given path, recording its errors. Named in-place policies
+// are taken from the policy map, where their errors are already recorded.
+func (e *Engine) resolvePolicy(spec *PolicySpec, path string) (Policy, bool) {
+	var policy Policy
+
+	switch {
+	case spec.InPlace != nil && spec.InPlace.Name != "":
+		if !e.rawPolicies.IsValid(spec.InPlace.Name) {
+			return Policy{}, false
 		}
 
-		if err := e.rawPolicies.Preprocess(&policy); err != nil {
-			return err
+		return e.instantiate(spec.InPlace.Name, nil, spec.Position, path)
+
+	case spec.InPlace != nil:
+		policy = *spec.InPlace
+
+	case spec.Ref != "":
+		if !e.rawPolicies.IsValid(spec.Ref) {
+			// invalid policies, and those including them, are already reported
+			if !e.rawPolicies.HasPolicy(spec.Ref) && !e.hasPolicyError(spec.Ref) {
+				e.addError(spec.Position, path, fmt.Errorf("policy %s not found", spec.Ref))
+			}
+
+			return Policy{}, false
 		}
 
-		compiler = compiler.And(policy.Expression)
+		return e.instantiate(spec.Ref, spec.Args, spec.Position, path)
+
+	default:
+		e.addError(spec.Position, path, fmt.Errorf("policy specification is empty"))
+		return Policy{}, false
 	}
 
-	if !compiler.IsEmpty() {
-		if e.config.Debug {
-			log.Debug().
-				Str("path", currentPath).
-				Str("expression", compiler.currentExpr).
-				Msg("adding policy")
+	if policy.Expression == "" {
+		e.addError(spec.Position, path, fmt.Errorf("policy expression is empty"))
+		return Policy{}, false
+	}
+
+	original := policy
+	err := e.rawPolicies.Preprocess(&policy)
+	if err == nil {
+		_, err = CompilePolicy(policy.Expression)
+	}
+
+	if err != nil {
+		if e.rawPolicies.includesInvalid(original) && ownExpressionError(original.Expression) == nil {
+			// the error is in an included policy, which is already reported
+			return Policy{}, false
 		}
 
-		compliledPolicy, err := compiler.Compile()
-		if err != nil {
-			return err
+		configErr := policyError(original, err)
+		configErr.File = e.config.File
+		configErr.Resource = path
+		if !configErr.Position.IsValid() {
+			configErr.Position = spec.Position
 		}
 
-		e.compiledPolicies[currentPath] = compliledPolicy
+		e.errs = append(e.errs, configErr)
+
+		return Policy{}, false
 	}
 
-	for _, child := range resource.Children {
-		if err := e.addResource(child, currentPath, compiler); err != nil {
-			return err
+	return policy, true
+}
+
+// instantiate returns the named valid policy, instantiated with the given
+// arguments if it is a template, recording the errors of the arguments.
+func (e *Engine) instantiate(
+	name string,
+	args map[string]any,
+	position Position,
+	path string,
+) (Policy, bool) {
+	policy, err := e.rawPolicies.Instantiate(name, args)
+	if err != nil {
+		e.addError(position, path, err)
+		return Policy{}, false
+	}
+
+	return policy, true
+}
+
+// hasPolicyError checks whether an error was recorded for the named policy,
+// so that references to it are not reported again.
+func (e *Engine) hasPolicyError(name string) bool {
+	return slices.ContainsFunc(e.errs, func(err *ConfigError) bool {
+		return err.Resource == "" && err.Policy == name
+	})
+}
+
+func (e *Engine) compileClauses(clauses []Clause) ([]CompiledPolicy, error) {
+	policies := make([]CompiledPolicy, len(clauses))
+
+	for i, clause := range clauses {
+		policy, ok := e.clausePolicies[clause.Expression]
+		if !ok {
+			var err error
+			if policy, err = CompilePolicy(clause.Expression); err != nil {
+				return nil, err
+			}
+
+			e.clausePolicies[clause.Expression] = policy
+		}
+
+		policies[i] = policy
+	}
+
+	return policies, nil
+}
+
+// failedClause returns the first clause of the resource that does not allow
+// the request.
+func (r *compiledResource) failedClause(env AuthzEnv) *Clause {
+	for i, policy := range r.clausePolicies {
+		if err := policy.Evaluate(env); err != nil {
+			return &r.clauses[i]
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func TestEngine(t *testing.T) {
@@ -136,3 +137,232 @@ func TestEngine(t *testing.T) {
 		}
 	})
 }
+
+func TestEngineWildcards(t *testing.T) {
+	type testRequest struct {
+		TenantId string
+	}
+
+	config := AuthzConfig{
+		PathSeparator:   "/",
+		EnforcementMode: EnforcementModeEnforcing,
+		Resources: []Resource{
+			{
+				Name: "/pkg.Service",
+				Children: []Resource{
+					{
+						Name:   "*",
+						Policy: &PolicySpec{InPlace: &Policy{Expression: "true"}},
+					},
+					{
+						Name:   "Admin",
+						Policy: &PolicySpec{InPlace: &Policy{Expression: "false"}},
+					},
+				},
+			},
+			{
+				Name: "/tenants",
+				Children: []Resource{
+					{
+						Name: "{tenant}",
+						Policy: &PolicySpec{
+							InPlace: &Policy{
+								Expression: "Params.tenant == Request.TenantId",
+							},
+						},
+						Children: []Resource{
+							{
+								Name: "docs",
+								Children: []Resource{
+									{
+										Name: "**",
+										Policy: &PolicySpec{
+											InPlace: &Policy{
+												Expression: `Params.tenant != "locked"`,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	t.Run("single segment wildcard", func(t *testing.T) {
+		err := engine.Authorize("/pkg.Service/Method", nil, nil)
+		require.NoError(t, err)
+
+		err = engine.Authorize("/pkg.Service/Method/Extra", nil, nil)
+		require.ErrorIs(t, err, ErrorNoPolicyForPath)
+	})
+
+	t.Run("literal wins over wildcard", func(t *testing.T) {
+		err := engine.Authorize("/pkg.Service/Admin", nil, nil)
+		require.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("param capture", func(t *testing.T) {
+		err := engine.Authorize("/tenants/acme", nil, testRequest{"acme"})
+		require.NoError(t, err)
+
+		err = engine.Authorize("/tenants/acme", nil, testRequest{"other"})
+		require.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("multi segment wildcard", func(t *testing.T) {
+		req := testRequest{"acme"}
+
+		for _, path := range []string{
+			"/tenants/acme/docs",
+			"/tenants/acme/docs/1",
+			"/tenants/acme/docs/1/GET",
+		} {
+			require.NoError(t, engine.Authorize(path, nil, req), path)
+		}
+
+		err := engine.Authorize("/tenants/locked/docs/1", nil, testRequest{"locked"})
+		require.ErrorIs(t, err, ErrUnauthorized)
+
+		err = engine.Authorize("/tenants/acme/docs/1", nil, testRequest{"other"})
+		require.ErrorIs(t, err, ErrUnauthorized)
+	})
+}
+
+func TestEngineResourceEnforcementMode(t *testing.T) {
+	shadow := EnforcementModeShadow
+	disabled := EnforcementModeDisabled
+	enforcing := EnforcementModeEnforcing
+
+	config := AuthzConfig{
+		PathSeparator:   ".",
+		EnforcementMode: EnforcementModeEnforcing,
+		Resources: []Resource{
+			{
+				Name:            "legacy",
+				EnforcementMode: &shadow,
+				Policy:          &PolicySpec{InPlace: &Policy{Expression: "false"}},
+				Children: []Resource{
+					{Name: "list"},
+					{
+						Name:            "delete",
+						EnforcementMode: &enforcing,
+					},
+				},
+			},
+			{
+				Name:            "health",
+				EnforcementMode: &disabled,
+				Policy:          &PolicySpec{InPlace: &Policy{Expression: "false"}},
+			},
+			{
+				Name:   "private",
+				Policy: &PolicySpec{InPlace: &Policy{Expression: "false"}},
+			},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	t.Run("shadow", func(t *testing.T) {
+		for _, path := range []string{"legacy", "legacy.list"} {
+			decision := engine.Decide(path, nil, nil)
+			require.True(t, decision.Allowed)
+			require.True(t, decision.Shadowed)
+			require.Equal(t, EnforcementModeShadow, decision.Mode)
+			require.ErrorIs(t, decision.Err, ErrUnauthorized)
+			require.NotNil(t, decision.FailedClause)
+			require.NoError(t, engine.Authorize(path, nil, nil))
+		}
+	})
+
+	t.Run("overridden by child", func(t *testing.T) {
+		decision := engine.Decide("legacy.delete", nil, nil)
+		require.False(t, decision.Allowed)
+		require.False(t, decision.Shadowed)
+		require.Equal(t, EnforcementModeEnforcing, decision.Mode)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.NoError(t, engine.Authorize("health", nil, nil))
+		require.Equal(t, EnforcementModeDisabled, engine.EnforcementModeOf("health"))
+	})
+
+	t.Run("global", func(t *testing.T) {
+		require.ErrorIs(t, engine.Authorize("private", nil, nil), ErrUnauthorized)
+
+		engine.SetEnforcementMode(EnforcementModeShadow)
+
+		decision := engine.Decide("unknown", nil, nil)
+		require.True(t, decision.Allowed)
+		require.True(t, decision.Shadowed)
+		require.ErrorIs(t, decision.Err, ErrorNoPolicyForPath)
+
+		require.NoError(t, engine.Authorize("private", nil, nil))
+		require.Error(t, engine.Authorize("legacy.delete", nil, nil))
+	})
+}
+
+func TestEngineStreamDirection(t *testing.T) {
+	recv := StreamDirectionRecv
+	both := StreamDirectionBoth
+
+	config := AuthzConfig{
+		PathSeparator: "/",
+		Resources: []Resource{
+			{
+				Name:            "/pkg.Chat",
+				StreamDirection: &recv,
+				Policy:          &PolicySpec{InPlace: &Policy{Expression: "true"}},
+				Children: []Resource{
+					{Name: "Subscribe"},
+					{Name: "Talk", StreamDirection: &both},
+				},
+			},
+			{
+				Name:   "/pkg.Other",
+				Policy: &PolicySpec{InPlace: &Policy{Expression: "true"}},
+			},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	require.Equal(t, StreamDirectionRecv, engine.StreamDirectionOf("/pkg.Chat/Subscribe"))
+	require.Equal(t, StreamDirectionBoth, engine.StreamDirectionOf("/pkg.Chat/Talk"))
+	require.Equal(t, StreamDirectionNone, engine.StreamDirectionOf("/pkg.Other"))
+	require.Equal(t, StreamDirectionNone, engine.StreamDirectionOf("/pkg.Unknown"))
+}
+
+func TestEngineResourceWithoutPolicy(t *testing.T) {
+	const configYAML = `
+pathSeparator: "."
+enforcementMode: enforcing
+resources:
+  - name: health
+    enforcementMode: disabled
+  - name: events
+    streamDirection: both
+`
+
+	var config AuthzConfig
+	require.NoError(t, yaml.Unmarshal([]byte(configYAML), &config))
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	require.NoError(t, engine.Authorize("health", nil, nil))
+	require.Equal(t, EnforcementModeDisabled, engine.EnforcementModeOf("health"))
+
+	require.Equal(t, StreamDirectionBoth, engine.StreamDirectionOf("events"))
+	require.ErrorIs(t, engine.Authorize("events", nil, nil), ErrorNoPolicyForPath)
+
+	require.Empty(t, engine.ProtectedResources())
+}
@@ -10,16 +10,36 @@ type AuthzEnv struct {
 	Config  *AuthzConfig
 	Claims  *recloak.Claims
 	Request any
+
+	// Parameters captured from the resource path.
+	Params map[string]string
 }
 
 // InRealmRole checks if the user has the given role in the realm.
 func (e AuthzEnv) InRealmRole(role string) bool {
-	return e.Claims.RealmAcess.HasRole(role)
+	return e.Claims != nil && e.Claims.RealmAcess.HasRole(role)
 }
 
 // InClientRole checks if the user has the given role for the given client.
 func (e AuthzEnv) InRole(role string) bool {
+	if e.Claims == nil || e.Config == nil {
+		return false
+	}
+
 	clientRoles, ok := e.Claims.ResourceAcess[e.Config.ClientID]
 
 	return ok && clientRoles.HasRole(role)
 }
+
+// HasScope checks if the token was granted the given scope.
+func (e AuthzEnv) HasScope(scope string) bool {
+	return e.Claims.HasScope(scope)
+}
+
+// Claim returns the value of the token claim with the given name, or nil if
+// the token has no such claim.
+func (e AuthzEnv) Claim(name string) any {
+	value, _ := e.Claims.Get(name)
+
+	return value
+}
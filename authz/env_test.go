@@ -36,6 +36,64 @@ func TestEnvInRole(t *testing.T) {
 	})
 }
 
+func TestEnvExtraClaims(t *testing.T) {
+	type testRequest struct {
+		TenantId string
+	}
+
+	env := AuthzEnv{
+		Config: &AuthzConfig{},
+		Claims: &recloak.Claims{
+			Extra: map[string]any{
+				"tenant_id": "acme",
+				"scope":     "openid orders:read",
+			},
+		},
+		Request: testRequest{TenantId: "acme"},
+	}
+
+	t.Run("extra claim member", func(t *testing.T) {
+		err := evalPolicy(`Claims.Extra.tenant_id == Request.TenantId`, env)
+		require.NoError(t, err)
+	})
+
+	t.Run("extra claim function", func(t *testing.T) {
+		err := evalPolicy(`Claim("tenant_id") == Request.TenantId`, env)
+		require.NoError(t, err)
+	})
+
+	t.Run("missing extra claim", func(t *testing.T) {
+		err := evalPolicy(`Claims.Extra.missing == Request.TenantId`, env)
+		require.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("other tenant", func(t *testing.T) {
+		env := env
+		env.Request = testRequest{TenantId: "other"}
+
+		err := evalPolicy(`Claims.Extra.tenant_id == Request.TenantId`, env)
+		require.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("has scope", func(t *testing.T) {
+		require.NoError(t, evalPolicy(`HasScope("orders:read")`, env))
+		require.Error(t, evalPolicy(`HasScope("orders:write")`, env))
+	})
+}
+
+func TestEnvWithoutClaims(t *testing.T) {
+	env := AuthzEnv{Config: &AuthzConfig{ClientID: "client"}}
+
+	for _, expr := range []string{
+		`InRealmRole("admin")`,
+		`InRole("user")`,
+		`HasScope("orders:read")`,
+		`Claim("tenant_id") != nil`,
+	} {
+		require.ErrorIs(t, evalPolicy(expr, env), ErrUnauthorized, expr)
+	}
+}
+
 func evalPolicy(expr string, env AuthzEnv) error {
 	policy, err := CompilePolicy(expr)
 	if err != nil {
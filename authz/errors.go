@@ -0,0 +1,240 @@
+package authz
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/file"
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a location in the YAML source of a configuration. A zero line
+// means that the location is unknown, and a zero column that only the line is
+// known.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// ConfigError is an error in an authorization configuration, located in its
+// YAML source where possible.
+type ConfigError struct {
+	// The path of the configuration file, if known.
+	File string
+
+	// The location of the error in the file. For expression errors, it is the
+	// location of the offending character of the original expression.
+	Position Position
+
+	// The full path of the resource of the error, if any.
+	Resource string
+
+	// The name of the policy of the error, if any.
+	Policy string
+
+	// The underlying error.
+	Err error
+}
+
+// ConfigErrors is a list of all the errors found in a configuration.
+type ConfigErrors []*ConfigError
+
+// IsValid checks whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as `line:column`, or `line` if the column is
+// unknown.
+func (p Position) String() string {
+	if p.Column == 0 {
+		return fmt.Sprint(p.Line)
+	}
+
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// compare compares the position with another one, in the order of the file.
+func (p Position) compare(other Position) int {
+	if p.Line != other.Line {
+		return cmp.Compare(p.Line, other.Line)
+	}
+
+	return cmp.Compare(p.Column, other.Column)
+}
+
+// at returns the position of the character at the given offset, in runes, of
+// an expression that starts at the position.
+func (p Position) at(expression string, offset int) Position {
+	line, column := 0, 0
+	for i, r := range []rune(expression) {
+		if i == offset {
+			break
+		}
+
+		if r == '\n' {
+			line++
+			column = 0
+		} else {
+			column++
+		}
+	}
+
+	switch {
+	case !p.IsValid():
+		return p
+
+	case p.Column == 0:
+		// block scalars keep the lines, but their indentation is unknown
+		return Position{Line: p.Line + line}
+
+	case line == 0:
+		return Position{Line: p.Line, Column: p.Column + column}
+
+	default:
+		return Position{Line: p.Line}
+	}
+}
+
+func (e *ConfigError) Error() string {
+	var sb strings.Builder
+
+	if e.File != "" {
+		sb.WriteString(e.File)
+		sb.WriteByte(':')
+	}
+
+	if e.Position.IsValid() {
+		sb.WriteString(e.Position.String())
+		sb.WriteByte(':')
+	}
+
+	if sb.Len() > 0 {
+		sb.WriteByte(' ')
+	}
+
+	if e.Resource != "" {
+		fmt.Fprintf(&sb, "resource `%s`: ", e.Resource)
+	}
+
+	if e.Policy != "" {
+		fmt.Fprintf(&sb, "policy `%s`: ", e.Policy)
+	}
+
+	// the location of expression errors is already given
+	var exprErr *file.Error
+	if errors.As(e.Err, &exprErr) && e.Position.IsValid() {
+		sb.WriteString(strings.TrimSpace(exprErr.Message))
+	} else {
+		sb.WriteString(e.Err.Error())
+	}
+
+	return sb.String()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Error returns the errors, one per line.
+func (e ConfigErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (e ConfigErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// policyError returns an error of the given policy. Expression errors in
+// the original expression of the policy, before its includes are expanded,
+// are located at their offending character.
+func policyError(policy Policy, err error) *ConfigError {
+	configErr := &ConfigError{
+		Position: policy.Position,
+		Policy:   policy.Name,
+		Err:      err,
+	}
+
+	var exprErr *file.Error
+	if errors.As(err, &exprErr) && policy.ExpressionPosition.IsValid() {
+		configErr.Position = policy.ExpressionPosition
+
+		if ownErr := ownExpressionError(policy.Expression); ownErr != nil {
+			configErr.Position = policy.ExpressionPosition.at(policy.Expression, ownErr.From)
+			configErr.Err = ownErr
+		}
+	}
+
+	return configErr
+}
+
+// ownExpressionError returns the first error in the given expression itself,
+// ignoring the policies that it includes, if any.
+func ownExpressionError(expression string) *file.Error {
+	masked, err := maskIncludes(expression)
+	if err != nil {
+		return nil
+	}
+
+	_, err = expr.Compile(
+		masked,
+		expr.Env(AuthzEnv{}),
+		expr.AllowUndefinedVariables(),
+		expr.AsBool(),
+	)
+
+	var exprErr *file.Error
+	if errors.As(err, &exprErr) {
+		return exprErr
+	}
+
+	return nil
+}
+
+// positionOf returns the position of the given node.
+func positionOf(node *yaml.Node) Position {
+	return Position{Line: node.Line, Column: node.Column}
+}
+
+// expressionPositionOf returns the position of the first character of the
+// expression of the given scalar node.
+func expressionPositionOf(node *yaml.Node) Position {
+	switch node.Style {
+	case yaml.LiteralStyle, yaml.FoldedStyle:
+		return Position{Line: node.Line + 1}
+
+	case yaml.SingleQuotedStyle, yaml.DoubleQuotedStyle:
+		return Position{Line: node.Line, Column: node.Column + 1}
+
+	default:
+		return positionOf(node)
+	}
+}
+
+// mappingValue returns the value of the given key of a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
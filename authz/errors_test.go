@@ -0,0 +1,78 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigErrors(t *testing.T) {
+	const configYAML = `
+pathSeparator: "."
+policies:
+  - name: broken
+    expression: InRole("a") &&
+  - name: uses_broken
+    expression: '@broken || InRole(1)'
+  - name: block
+    expression: |
+      InRole("a") &&
+        Request.
+  - name: valid
+    expression: InRole("a")
+resources:
+  - name: orders
+    policy: missing
+    children:
+      - name: get
+        policy: broken
+      - name: list
+        policy:
+          expression: "@valid && Claim(1)"
+  - displayName: unnamed
+  - name: health
+    policy: valid
+  - name: health
+    policy: valid
+  - name: wrapped
+    policy:
+      name: wraps_broken
+      expression: "@broken || true"
+`
+
+	var config AuthzConfig
+	require.NoError(t, yaml.Unmarshal([]byte(configYAML), &config))
+	config.File = "authz.yaml"
+
+	_, err := NewEngine(&config)
+	require.Error(t, err)
+
+	var errs ConfigErrors
+	require.True(t, errors.As(err, &errs))
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	require.Equal(t, []string{
+		"authz.yaml:5:30: policy `broken`: unexpected token EOF",
+		"authz.yaml:7:36: policy `uses_broken`: cannot use int as argument (type string) to call InRole",
+		"authz.yaml:11: policy `block`: unexpected end of expression",
+		"authz.yaml:16:13: resource `orders`: policy missing not found",
+		"authz.yaml:22:40: resource `orders.list`: cannot use int as argument (type string) to call Claim",
+		"authz.yaml:23:5: resource name is empty",
+		"authz.yaml:26:5: duplicate resource name: health",
+	}, messages)
+
+	require.Equal(t, "orders.list", errs[4].Resource)
+	require.Equal(t, Position{Line: 22, Column: 40}, errs[4].Position)
+}
+
+func TestPositionAt(t *testing.T) {
+	require.Equal(t, Position{Line: 3, Column: 9}, Position{Line: 3, Column: 5}.at("a && b", 4))
+	require.Equal(t, Position{Line: 5}, Position{Line: 3}.at("a &&\n\nb", 7))
+	require.Equal(t, Position{}, Position{}.at("a", 0))
+}
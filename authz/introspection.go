@@ -0,0 +1,191 @@
+package authz
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+)
+
+const (
+	// DefaultIntrospectionMaxTTL is how long an active introspection result is
+	// cached at most, if not configured.
+	DefaultIntrospectionMaxTTL = time.Minute
+
+	// DefaultIntrospectionInactiveTTL is how long an inactive introspection
+	// result is cached, if not configured.
+	DefaultIntrospectionInactiveTTL = 5 * time.Second
+
+	// DefaultIntrospectionCacheSize is the maximum number of cached
+	// introspection results, if not configured.
+	DefaultIntrospectionCacheSize = 10000
+)
+
+// IntrospectionCacheOptions is a struct that holds the configuration of an
+// introspection cache.
+type IntrospectionCacheOptions struct {
+	// How long an active result is cached at most. Results are never cached
+	// beyond the expiry of their token.
+	MaxTTL time.Duration `yaml:"maxTTL,omitempty"`
+
+	// How long an inactive result is cached.
+	InactiveTTL time.Duration `yaml:"inactiveTTL,omitempty"`
+
+	// The maximum number of cached results, beyond which the least recently
+	// used ones are evicted.
+	MaxSize int `yaml:"maxSize,omitempty"`
+}
+
+// IntrospectionCacheStats is a snapshot of the metrics of an introspection
+// cache.
+type IntrospectionCacheStats struct {
+	// The number of lookups that found a valid result.
+	Hits uint64
+
+	// The number of lookups that found no valid result.
+	Misses uint64
+
+	// The number of results evicted to make room for new ones.
+	Evictions uint64
+
+	// The number of results dropped because they expired.
+	Expirations uint64
+
+	// The number of cached results.
+	Size int
+}
+
+// IntrospectionCache is a size-bounded LRU cache of token introspection
+// results, keyed by the hash of the token. It is safe for concurrent use.
+type IntrospectionCache struct {
+	options IntrospectionCacheOptions
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List
+	stats   IntrospectionCacheStats
+}
+
+type introspectionCacheEntry struct {
+	key       [sha256.Size]byte
+	result    *gocloak.IntroSpectTokenResult
+	expiresAt time.Time
+}
+
+// NewIntrospectionCache creates a new introspection cache, using the default
+// value of any unset option.
+func NewIntrospectionCache(options IntrospectionCacheOptions) *IntrospectionCache {
+	if options.MaxTTL <= 0 {
+		options.MaxTTL = DefaultIntrospectionMaxTTL
+	}
+
+	if options.InactiveTTL <= 0 {
+		options.InactiveTTL = DefaultIntrospectionInactiveTTL
+	}
+
+	if options.MaxSize <= 0 {
+		options.MaxSize = DefaultIntrospectionCacheSize
+	}
+
+	return &IntrospectionCache{
+		options: options,
+		now:     time.Now,
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns the cached introspection result of the given token, if any.
+func (c *IntrospectionCache) Get(token string) (*gocloak.IntroSpectTokenResult, bool) {
+	key := sha256.Sum256([]byte(token))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*introspectionCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.removeLocked(elem)
+		c.stats.Expirations++
+		c.stats.Misses++
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+
+	return entry.result, true
+}
+
+// Put caches the introspection result of the given token.
+func (c *IntrospectionCache) Put(token string, result *gocloak.IntroSpectTokenResult) {
+	key := sha256.Sum256([]byte(token))
+	expiresAt := c.expiryOf(result)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*introspectionCacheEntry)
+		entry.result = result
+		entry.expiresAt = expiresAt
+		c.lru.MoveToFront(elem)
+
+		return
+	}
+
+	for c.lru.Len() >= c.options.MaxSize {
+		c.removeLocked(c.lru.Back())
+		c.stats.Evictions++
+	}
+
+	c.entries[key] = c.lru.PushFront(&introspectionCacheEntry{
+		key:       key,
+		result:    result,
+		expiresAt: expiresAt,
+	})
+}
+
+// Stats returns a snapshot of the metrics of the cache.
+func (c *IntrospectionCache) Stats() IntrospectionCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.lru.Len()
+
+	return stats
+}
+
+// expiryOf returns when the given result expires, which is bounded by the
+// expiry of its token for active results.
+func (c *IntrospectionCache) expiryOf(result *gocloak.IntroSpectTokenResult) time.Time {
+	now := c.now()
+
+	if result.Active == nil || !*result.Active {
+		return now.Add(c.options.InactiveTTL)
+	}
+
+	expiresAt := now.Add(c.options.MaxTTL)
+	if result.Exp != nil {
+		if tokenExpiresAt := time.Unix(int64(*result.Exp), 0); tokenExpiresAt.Before(expiresAt) {
+			expiresAt = tokenExpiresAt
+		}
+	}
+
+	return expiresAt
+}
+
+func (c *IntrospectionCache) removeLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*introspectionCacheEntry)
+	delete(c.entries, entry.key)
+}
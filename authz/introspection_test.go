@@ -0,0 +1,95 @@
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectionCache(t *testing.T) {
+	now := time.Now()
+
+	newCache := func(options IntrospectionCacheOptions) *IntrospectionCache {
+		cache := NewIntrospectionCache(options)
+		cache.now = func() time.Time { return now }
+
+		return cache
+	}
+
+	active := func(exp time.Time) *gocloak.IntroSpectTokenResult {
+		return &gocloak.IntroSpectTokenResult{
+			Active: gocloak.BoolP(true),
+			Exp:    gocloak.IntP(int(exp.Unix())),
+		}
+	}
+
+	t.Run("max ttl", func(t *testing.T) {
+		cache := newCache(IntrospectionCacheOptions{MaxTTL: time.Minute})
+		cache.Put("token", active(now.Add(time.Hour)))
+
+		result, ok := cache.Get("token")
+		require.True(t, ok)
+		require.True(t, *result.Active)
+
+		now = now.Add(time.Minute)
+
+		_, ok = cache.Get("token")
+		require.False(t, ok)
+		require.Equal(t, IntrospectionCacheStats{
+			Hits:        1,
+			Misses:      1,
+			Expirations: 1,
+		}, cache.Stats())
+	})
+
+	t.Run("token expiry", func(t *testing.T) {
+		cache := newCache(IntrospectionCacheOptions{MaxTTL: time.Hour})
+		cache.Put("token", active(now.Add(10*time.Second)))
+
+		now = now.Add(9 * time.Second)
+		_, ok := cache.Get("token")
+		require.True(t, ok)
+
+		now = now.Add(time.Second)
+		_, ok = cache.Get("token")
+		require.False(t, ok)
+	})
+
+	t.Run("inactive ttl", func(t *testing.T) {
+		cache := newCache(IntrospectionCacheOptions{InactiveTTL: time.Second})
+		cache.Put("token", &gocloak.IntroSpectTokenResult{Active: gocloak.BoolP(false)})
+
+		result, ok := cache.Get("token")
+		require.True(t, ok)
+		require.False(t, *result.Active)
+
+		now = now.Add(time.Second)
+		_, ok = cache.Get("token")
+		require.False(t, ok)
+	})
+
+	t.Run("lru eviction", func(t *testing.T) {
+		cache := newCache(IntrospectionCacheOptions{MaxSize: 2})
+		cache.Put("a", active(now.Add(time.Hour)))
+		cache.Put("b", active(now.Add(time.Hour)))
+
+		_, ok := cache.Get("a")
+		require.True(t, ok)
+
+		cache.Put("c", active(now.Add(time.Hour)))
+
+		_, ok = cache.Get("b")
+		require.False(t, ok)
+
+		for _, token := range []string{"a", "c"} {
+			_, ok = cache.Get(token)
+			require.True(t, ok)
+		}
+
+		stats := cache.Stats()
+		require.Equal(t, 2, stats.Size)
+		require.EqualValues(t, 1, stats.Evictions)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 )
@@ -11,40 +12,111 @@ import (
 // PolicyIncludePrefix is the prefix that indicates a policy include.
 const PolicyIncludePrefix = '@'
 
-var policyNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+// policyNamePattern is the pattern of policy names, which may contain `-`.
+// Includes may contain `-` too, so `@limit-1` includes the policy `limit-1`,
+// and a subtraction must be separated from an include, as in `@limit - 1`.
+var policyNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
 // PolicyMap is a set of policies.
 type PolicyMap struct {
 	policies map[string]Policy
+
+	// names of the policies in the order they were added
+	order []string
+
+	// expressions of the resolved policies, with their includes expanded
+	expanded map[string]string
+
+	// names of the resolved policies, each after the policies it includes
+	resolved []string
+
+	// names of the policies whose includes do not resolve, or whose
+	// expressions do not compile
+	invalid map[string]struct{}
 }
 
 // NewEmptyPolicyMap creates a new empty policy set.
 func NewEmptyPolicyMap() PolicyMap {
 	return PolicyMap{
 		policies: make(map[string]Policy),
+		expanded: make(map[string]string),
+		invalid:  make(map[string]struct{}),
 	}
 }
 
 // NewPolicyMap creates a new policy set.
 func NewPolicyMap(config *AuthzConfig) (PolicyMap, error) {
+	policyMap, errs := newPolicyMap(config)
+	if len(errs) > 0 {
+		return PolicyMap{}, errs
+	}
+
+	return policyMap, nil
+}
+
+// newPolicyMap creates a new policy set, collecting the errors of all invalid
+// policies, which are left out of the set.
+func newPolicyMap(config *AuthzConfig) (PolicyMap, ConfigErrors) {
 	policyMap := NewEmptyPolicyMap()
 
-	for _, policy := range config.Policies {
+	var errs ConfigErrors
+	add := func(policy Policy) {
 		if err := policyMap.Add(policy); err != nil {
-			return PolicyMap{}, err
+			errs = append(errs, policyError(policy, err))
+		}
+	}
+
+	for _, policy := range config.Policies {
+		add(policy)
+	}
+
+	var addFromResources func(resources []Resource)
+	addFromResources = func(resources []Resource) {
+		for _, resource := range resources {
+			if resource.Policy != nil &&
+				resource.Policy.InPlace != nil &&
+				resource.Policy.InPlace.Name != "" {
+				add(*resource.Policy.InPlace)
+			}
+
+			addFromResources(resource.Children)
 		}
 	}
+	addFromResources(config.Resources)
+
+	errs = append(errs, policyMap.resolve()...)
 
-	for _, resource := range config.Resources {
-		if err := policyMap.AddFromResource(&resource); err != nil {
-			return PolicyMap{}, err
+	// included policies are compiled first, so that errors in them are not
+	// reported again for the policies that include them
+	for _, name := range policyMap.resolved {
+		if err := policyMap.check(name); err != nil {
+			policy := policyMap.policies[name]
+			policyMap.invalid[name] = struct{}{}
+
+			if policyMap.includesInvalid(policy) && ownExpressionError(policy.Expression) == nil {
+				// the error is in an included policy, which is already reported
+				continue
+			}
+
+			errs = append(errs, policyError(policy, err))
 		}
 	}
 
-	return policyMap, nil
+	// errors are found in the order of the include graph, but are reported
+	// in the order of the configuration
+	slices.SortStableFunc(errs, func(a, b *ConfigError) int {
+		return a.Position.compare(b.Position)
+	})
+
+	for _, err := range errs {
+		err.File = config.File
+	}
+
+	return policyMap, errs
 }
 
-// Add adds a policy to the set.
+// Add adds a policy to the set. The policies it includes need not be added
+// yet, as includes are expanded by `PolicyMap.Resolve`.
 func (s *PolicyMap) Add(policy Policy) error {
 	if !policyNamePattern.MatchString(policy.Name) {
 		return fmt.Errorf("invalid policy name: %s", policy.Name)
@@ -54,11 +126,16 @@ func (s *PolicyMap) Add(policy Policy) error {
 		return fmt.Errorf("duplicate policy name: %s", policy.Name)
 	}
 
-	if err := s.Preprocess(&policy); err != nil {
+	if _, err := scanIncludes(policy.Expression); err != nil {
+		return err
+	}
+
+	if err := policy.validateParameters(); err != nil {
 		return err
 	}
 
 	s.policies[policy.Name] = policy
+	s.order = append(s.order, policy.Name)
 
 	return nil
 }
@@ -83,9 +160,14 @@ func (s *PolicyMap) AddFromResource(resource *Resource) error {
 	return nil
 }
 
-// Get gets a policy from the set by name.
+// Get gets a policy from the set by name, with its includes expanded if the
+// set is resolved.
 func (p *PolicyMap) Get(name string) (policy Policy, ok bool) {
 	policy, ok = p.policies[name]
+	if expanded, resolved := p.expanded[name]; resolved {
+		policy.Expression = expanded
+	}
+
 	return
 }
 
@@ -96,82 +178,317 @@ func (p *PolicyMap) HasPolicy(name string) bool {
 	return ok
 }
 
-func (p *PolicyMap) Preprocess(policy *Policy) error {
+// IsValid checks whether the named policy was added to the set, and whether
+// its expression compiles, if checked.
+func (p *PolicyMap) IsValid(name string) bool {
+	_, invalid := p.invalid[name]
+
+	return p.HasPolicy(name) && !invalid
+}
+
+// includesInvalid checks whether the given policy includes a policy whose
+// expression does not compile.
+func (p *PolicyMap) includesInvalid(policy Policy) bool {
 	includes, err := getIncludes(policy.Expression)
 	if err != nil {
-		return err
+		return false
+	}
+
+	for _, name := range includes {
+		if _, ok := p.invalid[name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check checks that the expression of the named resolved policy compiles,
+// with its parameters substituted with placeholders if it is a template.
+func (p *PolicyMap) check(name string) error {
+	_, err := p.compile(name)
+
+	return err
+}
+
+// compile compiles the expression of the named resolved policy, with its
+// parameters substituted with placeholders if it is a template.
+func (p *PolicyMap) compile(name string) (CompiledPolicy, error) {
+	policy, _ := p.Get(name)
+
+	expression, err := substituteParameters(policy, policy.placeholders())
+	if err != nil {
+		return CompiledPolicy{}, err
 	}
 
-	ppExpr := policy.Expression
+	return CompilePolicy(expression)
+}
+
+// Resolve expands the includes of all policies of the set, transitively and
+// regardless of the order in which they were added. It returns an error for
+// every include that does not resolve and for every include cycle; the
+// policies involved, and those including them, are left unresolved.
+func (p *PolicyMap) Resolve() error {
+	if errs := p.resolve(); len(errs) > 0 {
+		return errs
+	}
 
-	for _, includedName := range includes {
-		if includedName == policy.Name {
-			return fmt.Errorf("policy `%s` includes itself", policy.Name)
+	return nil
+}
+
+// resolve expands the includes of all policies of the set in topological
+// order, by a depth-first traversal of their include graph.
+func (p *PolicyMap) resolve() ConfigErrors {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var errs ConfigErrors
+
+	p.resolved = p.resolved[:0]
+	states := make(map[string]int, len(p.policies))
+	path := make([]string, 0)
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		if states[name] == visited {
+			_, ok := p.expanded[name]
+			return ok
 		}
 
-		includedPolicy, ok := p.Get(includedName)
+		states[name] = visiting
+		path = append(path, name)
+
+		policy := p.policies[name]
+		includes, _ := scanIncludes(policy.Expression)
+
+		ok := true
+		for _, include := range includes {
+			switch {
+			case !p.HasPolicy(include.name):
+				errs = append(errs, policyError(policy, unresolvedError(include.name, name)))
+				ok = false
+
+			case states[include.name] == visiting:
+				cycle := slices.Clone(path[slices.Index(path, include.name):])
+				errs = append(errs, policyError(policy, cycleError(append(cycle, include.name))))
+				ok = false
+
+			case !visit(include.name):
+				// the error is in an included policy, which is already reported
+				ok = false
+			}
+		}
+
+		path = path[:len(path)-1]
+		states[name] = visited
+
+		if !ok {
+			p.invalid[name] = struct{}{}
+			return false
+		}
+
+		p.expanded[name] = expandIncludes(policy.Expression, includes, p.expanded)
+		p.resolved = append(p.resolved, name)
+
+		return true
+	}
+
+	for _, name := range p.order {
+		if states[name] == unvisited {
+			visit(name)
+		}
+	}
+
+	return errs
+}
+
+// Preprocess expands the includes of the given policy, which is not in the
+// set, with the resolved policies of the set.
+func (p *PolicyMap) Preprocess(policy *Policy) error {
+	includes, err := scanIncludes(policy.Expression)
+	if err != nil {
+		return err
+	}
+
+	expressions := make(map[string]string, len(includes))
+	for _, include := range includes {
+		if include.name == policy.Name {
+			return cycleError([]string{policy.Name, policy.Name})
+		}
+
+		includedPolicy, ok := p.Get(include.name)
 		if !ok {
-			return fmt.Errorf(
-				"unresolved reference `%s` in %s",
-				includedName,
-				policy.Name,
-			)
+			return unresolvedError(include.name, policy.Name)
 		}
 
-		ppExpr = strings.ReplaceAll(
-			ppExpr,
-			fmt.Sprintf("@%s", includedPolicy.Name),
-			fmt.Sprintf("(%s)", includedPolicy.Expression),
-		)
+		expressions[include.name] = includedPolicy.Expression
 	}
 
-	policy.Expression = ppExpr
+	policy.Expression = expandIncludes(policy.Expression, includes, expressions)
 
 	return nil
 }
 
+// expandIncludes replaces each of the given includes of the expression with
+// the parenthesized expression of the included policy.
+func expandIncludes(
+	expr string,
+	includes []reference,
+	expressions map[string]string,
+) string {
+	return replaceReferences(expr, includes, func(name string) string {
+		return "(" + expressions[name] + ")"
+	})
+}
+
+// replaceReferences replaces each of the given references of the expression
+// with its replacement.
+func replaceReferences(
+	expr string,
+	refs []reference,
+	replacement func(name string) string,
+) string {
+	var b strings.Builder
+
+	last := 0
+	for _, ref := range refs {
+		b.WriteString(expr[last:ref.start])
+		b.WriteString(replacement(ref.name))
+
+		last = ref.end
+	}
+
+	b.WriteString(expr[last:])
+
+	return b.String()
+}
+
+func unresolvedError(name, policy string) error {
+	return fmt.Errorf("unresolved reference `%s` in %s", name, policy)
+}
+
+func cycleError(cycle []string) error {
+	return fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+}
+
 func getIncludes(expr string) ([]string, error) {
-	refs := make([]string, 0)
+	includes, err := scanIncludes(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, len(includes))
+	for i, include := range includes {
+		refs[i] = include.name
+	}
 
-	inSingleQuote := false
-	inDoubleQuote := false
+	return refs, nil
+}
+
+// reference is a reference to a policy or a parameter in an expression,
+// spanning the bytes `[start, end)` including its prefix.
+type reference struct {
+	name       string
+	start, end int
+}
+
+// scanIncludes returns the includes of the given expression, ignoring quoted
+// strings.
+func scanIncludes(expr string) ([]reference, error) {
+	return scanReferences(expr, "policy", PolicyIncludePrefix, func(c byte) bool {
+		return c == '-'
+	})
+}
+
+// scanReferences returns the references of the given kind in the expression,
+// which are names of letters, digits, underscores and the extra allowed
+// characters following the given prefix, ignoring single-quoted,
+// double-quoted and raw strings, and escaped characters in them.
+func scanReferences(
+	expr string,
+	kind string,
+	prefix byte,
+	allowed func(c byte) bool,
+) ([]reference, error) {
+	refs := make([]reference, 0)
+
+	// the quote character of the current string, if any
+	var quote byte
 
 	for i := 0; i < len(expr); i++ {
-		switch expr[i] {
-		case '\'':
-			inSingleQuote = !inSingleQuote
+		switch {
+		case quote == '`':
+			// raw strings have no escapes
+			if expr[i] == quote {
+				quote = 0
+			}
 
-		case '"':
-			inDoubleQuote = !inDoubleQuote
-		}
+			continue
 
-		if inSingleQuote || inDoubleQuote {
+		case quote != 0:
+			if expr[i] == '\\' {
+				i++
+			} else if expr[i] == quote {
+				quote = 0
+			}
+
+			continue
+
+		case expr[i] == '\'' || expr[i] == '"' || expr[i] == '`':
+			quote = expr[i]
 			continue
 		}
 
-		if expr[i] == PolicyIncludePrefix {
+		if expr[i] == prefix {
+			start := i
+
 			i += 1
 			j := i
 
 			for ; j < len(expr); j++ {
 				if !unicode.IsLetter(rune(expr[j])) &&
 					!unicode.IsDigit(rune(expr[j])) &&
-					expr[j] != '_' {
+					expr[j] != '_' &&
+					!allowed(expr[j]) {
 					break
 				}
 			}
 
 			if i == j {
-				return nil, errors.New("empty policy name")
+				return nil, fmt.Errorf("empty %s name", kind)
 			}
 
-			refs = append(refs, expr[i:j])
+			refs = append(refs, reference{name: expr[i:j], start: start, end: j})
 		}
 	}
 
-	if inSingleQuote || inDoubleQuote {
+	if quote != 0 {
 		return nil, errors.New("unterminated quote")
 	}
 
 	return refs, nil
 }
+
+// maskIncludes replaces the includes of the given expression with identifiers
+// of the same length, so that it can be checked before the includes are
+// expanded without shifting its offsets.
+func maskIncludes(expr string) (string, error) {
+	includes, err := scanIncludes(expr)
+	if err != nil {
+		return "", err
+	}
+
+	masked := []byte(expr)
+	for _, include := range includes {
+		for i := include.start; i < include.end; i++ {
+			if masked[i] == PolicyIncludePrefix || masked[i] == '-' {
+				masked[i] = '_'
+			}
+		}
+	}
+
+	return string(masked), nil
+}
@@ -39,3 +39,151 @@ func TestPolicyMapAdd(t *testing.T) {
 		require.Equal(t, policy, policyMap.policies[policy.Name])
 	}
 }
+
+func TestPolicyMapResolve(t *testing.T) {
+	newMap := func(t *testing.T, policies ...Policy) PolicyMap {
+		policyMap := NewEmptyPolicyMap()
+		for _, policy := range policies {
+			require.NoError(t, policyMap.Add(policy))
+		}
+
+		return policyMap
+	}
+
+	t.Run("forward references are expanded transitively", func(t *testing.T) {
+		policyMap := newMap(t,
+			Policy{Name: "a", Expression: "@b && @c"},
+			Policy{Name: "b", Expression: "@c || false"},
+			Policy{Name: "c", Expression: "true"},
+		)
+		require.NoError(t, policyMap.Resolve())
+
+		a, ok := policyMap.Get("a")
+		require.True(t, ok)
+		require.Equal(t, "((true) || false) && (true)", a.Expression)
+		require.Equal(t, []string{"c", "b", "a"}, policyMap.resolved)
+	})
+
+	t.Run("only whole names are replaced", func(t *testing.T) {
+		policyMap := newMap(t,
+			Policy{Name: "admin", Expression: "InRole('admin')"},
+			Policy{Name: "admin_readonly", Expression: "InRole('reader')"},
+			Policy{Name: "any", Expression: "@admin_readonly || @admin || '@admin' == ''"},
+		)
+		require.NoError(t, policyMap.Resolve())
+
+		policy, _ := policyMap.Get("any")
+		require.Equal(
+			t,
+			"(InRole('reader')) || (InRole('admin')) || '@admin' == ''",
+			policy.Expression,
+		)
+	})
+
+	t.Run("cycles are reported with their path", func(t *testing.T) {
+		policyMap := newMap(t,
+			Policy{Name: "entry", Expression: "@a"},
+			Policy{Name: "a", Expression: "@b"},
+			Policy{Name: "b", Expression: "@c"},
+			Policy{Name: "c", Expression: "@a"},
+			Policy{Name: "self", Expression: "@self"},
+			Policy{Name: "other", Expression: "true"},
+		)
+
+		var errs ConfigErrors
+		require.ErrorAs(t, policyMap.Resolve(), &errs)
+		require.Len(t, errs, 2)
+		require.Equal(t, "c", errs[0].Policy)
+		require.EqualError(t, errs[0].Err, "include cycle: a -> b -> c -> a")
+		require.Equal(t, "self", errs[1].Policy)
+		require.EqualError(t, errs[1].Err, "include cycle: self -> self")
+
+		for _, name := range []string{"entry", "a", "b", "c", "self"} {
+			require.False(t, policyMap.IsValid(name), name)
+		}
+		require.True(t, policyMap.IsValid("other"))
+	})
+
+	t.Run("unresolved references are reported", func(t *testing.T) {
+		policyMap := newMap(t,
+			Policy{Name: "a", Expression: "@b"},
+			Policy{Name: "b", Expression: "@missing"},
+		)
+
+		var errs ConfigErrors
+		require.ErrorAs(t, policyMap.Resolve(), &errs)
+		require.Len(t, errs, 1)
+		require.EqualError(t, errs[0].Err, "unresolved reference `missing` in b")
+		require.False(t, policyMap.IsValid("a"))
+	})
+}
+
+func TestScanIncludes(t *testing.T) {
+	testData := []struct {
+		expr     string
+		expected []string
+		err      bool
+	}{
+		{expr: `@a && @b-c`, expected: []string{"a", "b-c"}},
+		{expr: `"it's" == "x" && @admin`, expected: []string{"admin"}},
+		{expr: `'say "hi' == 'x' || @admin`, expected: []string{"admin"}},
+		{expr: `"say \"@hi\"" == "x" || @admin`, expected: []string{"admin"}},
+		{expr: `'it\'s @me' == 'x' || @admin`, expected: []string{"admin"}},
+		{expr: `"a\\" == "x" || @admin`, expected: []string{"admin"}},
+		{expr: "`raw \\` == '@x' || @admin", expected: []string{"admin"}},
+		{expr: "`it's` == \"@x\" || @admin", expected: []string{"admin"}},
+		{expr: `"@admin"`, expected: []string{}},
+		{expr: `"unterminated @admin`, err: true},
+		{expr: `'it\' || @admin`, err: true},
+		{expr: `@ && true`, err: true},
+	}
+
+	for _, data := range testData {
+		actual, err := getIncludes(data.expr)
+		if data.err {
+			require.Error(t, err, data.expr)
+			continue
+		}
+
+		require.NoError(t, err, data.expr)
+		require.Equal(t, data.expected, actual, data.expr)
+	}
+}
+
+func TestPolicyNamesWithHyphens(t *testing.T) {
+	config := AuthzConfig{
+		PathSeparator: ".",
+		Policies: []Policy{
+			{Name: "limit", Expression: "true"},
+			{Name: "limit-1", Expression: "false"},
+			{Name: "allow-all", Expression: "@limit"},
+		},
+		Resources: []Resource{
+			{
+				Name:   "open",
+				Policy: &PolicySpec{Ref: "allow-all"},
+			},
+			{
+				Name: "limited",
+				Policy: &PolicySpec{
+					InPlace: &Policy{Name: "limited", Expression: "@limit-1"},
+				},
+			},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	require.NoError(t, engine.Authorize("open", nil, nil))
+
+	// `@limit-1` includes the policy `limit-1`, rather than subtracting 1
+	// from `@limit`
+	require.Error(t, engine.Authorize("limited", nil, nil))
+
+	config.Policies = config.Policies[:1]
+	config.Resources = config.Resources[1:]
+
+	_, err = NewEngine(&config)
+	require.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// SingleSegmentWildcard is a resource name that matches any single path
+	// segment.
+	SingleSegmentWildcard = "*"
+
+	// MultiSegmentWildcard is a resource name that matches any number of path
+	// segments, including none.
+	MultiSegmentWildcard = "**"
+)
+
+// segment ranks, from the least specific to the most specific.
+const (
+	rankMultiWildcard = iota
+	rankSingleWildcard
+	rankParam
+	rankLiteral
+)
+
+// pathPattern is a resource path, split on the path separator, that may
+// contain wildcards and `{param}` captures.
+type pathPattern struct {
+	segments []string
+	ranks    []int
+}
+
+// parsePathPattern parses the given resource path into a pattern.
+func parsePathPattern(path, separator string) (pathPattern, error) {
+	segments := splitPath(path, separator)
+	ranks := make([]int, len(segments))
+	params := make(map[string]struct{})
+
+	for i, segment := range segments {
+		switch {
+		case segment == MultiSegmentWildcard:
+			ranks[i] = rankMultiWildcard
+
+		case segment == SingleSegmentWildcard:
+			ranks[i] = rankSingleWildcard
+
+		case isParamSegment(segment):
+			name := paramName(segment)
+			if !policyNamePattern.MatchString(name) {
+				return pathPattern{}, fmt.Errorf(
+					"invalid parameter name `%s` in resource path `%s`",
+					name,
+					path,
+				)
+			}
+
+			if _, ok := params[name]; ok {
+				return pathPattern{}, fmt.Errorf(
+					"duplicate parameter `%s` in resource path `%s`",
+					name,
+					path,
+				)
+			}
+			params[name] = struct{}{}
+
+			ranks[i] = rankParam
+
+		default:
+			ranks[i] = rankLiteral
+		}
+	}
+
+	return pathPattern{segments: segments, ranks: ranks}, nil
+}
+
+// isLiteral checks whether the pattern has no wildcards or parameters.
+func (p pathPattern) isLiteral() bool {
+	for _, rank := range p.ranks {
+		if rank != rankLiteral {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match matches the given path segments against the pattern, returning the
+// captured parameters on success.
+//
+// Matching backtracks over the segments consumed by `**`, remembering the
+// positions that failed to match, so that it takes polynomial time whatever
+// the number of wildcards.
+func (p pathPattern) match(segments []string) (map[string]string, bool) {
+	m := patternMatcher{
+		pattern:  p,
+		segments: segments,
+		params:   make(map[string]string),
+		failed:   make([]bool, (len(p.segments)+1)*(len(segments)+1)),
+	}
+
+	if !m.matchFrom(0, 0) {
+		return nil, false
+	}
+
+	return m.params, true
+}
+
+// patternMatcher is the state of the match of path segments against a
+// pattern.
+type patternMatcher struct {
+	pattern  pathPattern
+	segments []string
+	params   map[string]string
+
+	// whether the pattern segments from an index failed to match the path
+	// segments from a position, indexed by `idx*(len(segments)+1) + pos`
+	failed []bool
+}
+
+// matchFrom matches the pattern segments from the given index against the
+// path segments from the given position.
+func (m *patternMatcher) matchFrom(idx, pos int) bool {
+	state := idx*(len(m.segments)+1) + pos
+	if m.failed[state] {
+		return false
+	}
+
+	if m.matchSegment(idx, pos) {
+		return true
+	}
+
+	m.failed[state] = true
+
+	return false
+}
+
+func (m *patternMatcher) matchSegment(idx, pos int) bool {
+	p := m.pattern
+	if idx == len(p.segments) {
+		return pos == len(m.segments)
+	}
+
+	remaining := len(m.segments) - pos
+
+	switch p.ranks[idx] {
+	case rankMultiWildcard:
+		for i := pos; i <= len(m.segments); i++ {
+			if m.matchFrom(idx+1, i) {
+				return true
+			}
+		}
+
+		return false
+
+	case rankSingleWildcard:
+		return remaining > 0 && m.matchFrom(idx+1, pos+1)
+
+	case rankParam:
+		if remaining == 0 {
+			return false
+		}
+
+		name := paramName(p.segments[idx])
+		m.params[name] = m.segments[pos]
+
+		if m.matchFrom(idx+1, pos+1) {
+			return true
+		}
+
+		delete(m.params, name)
+
+		return false
+
+	default:
+		return remaining > 0 &&
+			m.segments[pos] == p.segments[idx] &&
+			m.matchFrom(idx+1, pos+1)
+	}
+}
+
+// moreSpecific checks whether the pattern is more specific than the other one.
+//
+// Patterns are compared segment by segment, where literals are more specific
+// than parameters, parameters are more specific than `*` and `*` is more
+// specific than `**`. Of two patterns with equal leading segments, the longer
+// one is more specific.
+func (p pathPattern) moreSpecific(other pathPattern) bool {
+	for i := 0; i < len(p.ranks) && i < len(other.ranks); i++ {
+		if p.ranks[i] != other.ranks[i] {
+			return p.ranks[i] > other.ranks[i]
+		}
+	}
+
+	return len(p.ranks) > len(other.ranks)
+}
+
+func splitPath(path, separator string) []string {
+	if separator == "" {
+		return []string{path}
+	}
+
+	return strings.Split(path, separator)
+}
+
+func isParamSegment(segment string) bool {
+	return len(segment) > 2 &&
+		strings.HasPrefix(segment, "{") &&
+		strings.HasSuffix(segment, "}")
+}
+
+func paramName(segment string) string {
+	return segment[1 : len(segment)-1]
+}
@@ -0,0 +1,115 @@
+package authz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathPatternMatch(t *testing.T) {
+	testData := []struct {
+		pattern  string
+		path     string
+		matches  bool
+		expected map[string]string
+	}{
+		{pattern: "/pkg.Service/Method", path: "/pkg.Service/Method", matches: true},
+		{pattern: "/pkg.Service/Method", path: "/pkg.Service/Other", matches: false},
+		{pattern: "/pkg.Service/*", path: "/pkg.Service/Method", matches: true},
+		{pattern: "/pkg.Service/*", path: "/pkg.Service", matches: false},
+		{pattern: "/pkg.Service/*", path: "/pkg.Service/a/b", matches: false},
+		{pattern: "/tenants/**", path: "/tenants", matches: true},
+		{pattern: "/tenants/**", path: "/tenants/acme/docs/1", matches: true},
+		{pattern: "/**/docs", path: "/tenants/acme/docs", matches: true},
+		{pattern: "/**/docs", path: "/tenants/acme/users", matches: false},
+		{
+			pattern:  "/tenants/{tenant}/docs/**",
+			path:     "/tenants/acme/docs/1/GET",
+			matches:  true,
+			expected: map[string]string{"tenant": "acme"},
+		},
+		{
+			pattern:  "/tenants/{tenant}/docs/{doc}",
+			path:     "/tenants/acme/docs/1",
+			matches:  true,
+			expected: map[string]string{"tenant": "acme", "doc": "1"},
+		},
+		{pattern: "/tenants/{tenant}/docs", path: "/tenants/docs", matches: false},
+		{
+			pattern:  "/**/{kind}/**/GET",
+			path:     "/tenants/acme/docs/GET",
+			matches:  true,
+			expected: map[string]string{"kind": "tenants"},
+		},
+		{pattern: "/**/a/**/b", path: "/a/b/a", matches: false},
+	}
+
+	for _, data := range testData {
+		pattern, err := parsePathPattern(data.pattern, "/")
+		require.NoError(t, err)
+
+		params, ok := pattern.match(splitPath(data.path, "/"))
+		require.Equal(t, data.matches, ok, "%s ~ %s", data.pattern, data.path)
+
+		if data.matches && data.expected != nil {
+			require.Equal(t, data.expected, params)
+		}
+	}
+}
+
+func TestPathPatternMatchManyWildcards(t *testing.T) {
+	pattern, err := parsePathPattern(strings.Repeat("/**/a", 10)+"/b", "/")
+	require.NoError(t, err)
+
+	path := splitPath(strings.Repeat("/a", 100), "/")
+
+	// backtracking over every split of the path between the wildcards would
+	// not terminate in any reasonable time
+	_, ok := pattern.match(path)
+	require.False(t, ok)
+
+	_, ok = pattern.match(append(path, "b"))
+	require.True(t, ok)
+}
+
+func TestParsePathPatternErrors(t *testing.T) {
+	_, err := parsePathPattern("/tenants/{tenant}/users/{tenant}", "/")
+	require.Error(t, err)
+
+	_, err = parsePathPattern("/tenants/{ten ant}", "/")
+	require.Error(t, err)
+}
+
+func TestPathPatternSpecificity(t *testing.T) {
+	// from the most specific to the least specific
+	ordered := []string{
+		"/tenants/acme/docs",
+		"/tenants/acme/{kind}",
+		"/tenants/acme/*",
+		"/tenants/acme/**",
+		"/tenants/{tenant}/docs",
+		"/tenants/*/docs",
+		"/tenants/**",
+		"/**",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			lhs, err := parsePathPattern(ordered[i], "/")
+			require.NoError(t, err)
+
+			rhs, err := parsePathPattern(ordered[j], "/")
+			require.NoError(t, err)
+
+			require.Equal(
+				t,
+				i < j,
+				lhs.moreSpecific(rhs),
+				"%s > %s",
+				ordered[i],
+				ordered[j],
+			)
+		}
+	}
+}
@@ -16,13 +16,41 @@ type Policy struct {
 
 	// The description of the policy.
 	Expression string `yaml:"expression"`
+
+	// The parameters of the policy, if it is a template.
+	Parameters []PolicyParameter `yaml:"parameters,omitempty" faker:"-"`
+
+	// The location of the policy in the YAML source, if decoded from one.
+	Position Position `yaml:"-" faker:"-"`
+
+	// The location of the first character of the expression in the YAML
+	// source, if decoded from one.
+	ExpressionPosition Position `yaml:"-" faker:"-"`
 }
 
 // PolicySpec is a struct that enables to specify a policy either in place or
-// by reference.
+// by reference, with the arguments of the referenced policy template, if any.
 type PolicySpec struct {
-	InPlace *Policy `yaml:"inPlace,omitempty"`
-	Ref     string  `yaml:"policyRef,omitempty"`
+	InPlace *Policy        `yaml:"inPlace,omitempty"`
+	Ref     string         `yaml:"policyRef,omitempty"`
+	Args    map[string]any `yaml:"args,omitempty" faker:"-"`
+
+	// The location of the spec in the YAML source, if decoded from one.
+	Position Position `yaml:"-" faker:"-"`
+}
+
+func (p *Policy) UnmarshalYAML(value *yaml.Node) error {
+	type rawPolicy Policy
+	if err := value.Decode((*rawPolicy)(p)); err != nil {
+		return err
+	}
+
+	p.Position = positionOf(value)
+	if expression := mappingValue(value, "expression"); expression != nil {
+		p.ExpressionPosition = expressionPositionOf(expression)
+	}
+
+	return nil
 }
 
 func (s *PolicySpec) UnmarshalYAML(value *yaml.Node) error {
@@ -30,6 +58,8 @@ func (s *PolicySpec) UnmarshalYAML(value *yaml.Node) error {
 		return nil
 	}
 
+	s.Position = positionOf(value)
+
 	switch value.Kind {
 	case yaml.ScalarNode:
 		if err := value.Decode(&s.Ref); err != nil {
@@ -37,6 +67,19 @@ func (s *PolicySpec) UnmarshalYAML(value *yaml.Node) error {
 		}
 
 	case yaml.MappingNode:
+		if mappingValue(value, "ref") != nil {
+			var ref struct {
+				Ref  string         `yaml:"ref"`
+				Args map[string]any `yaml:"args"`
+			}
+			if err := value.Decode(&ref); err != nil {
+				return err
+			}
+			s.Ref, s.Args = ref.Ref, ref.Args
+
+			return nil
+		}
+
 		var inPlace Policy
 		if err := value.Decode(&inPlace); err != nil {
 			return err
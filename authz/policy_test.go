@@ -21,6 +21,9 @@ expression: %s
 	err := faker.FakeData(&expected)
 	require.NoError(t, err)
 
+	expected.Position = Position{Line: 3, Column: 1}
+	expected.ExpressionPosition = Position{Line: 5, Column: 13}
+
 	expectedYAML := fmt.Sprintf(
 		expectedYAMLFmt,
 		expected.Name,
@@ -48,6 +51,9 @@ expression: %s
 	err := faker.FakeData(&expected)
 	require.NoError(t, err)
 
+	expected.Position = Position{Line: 3, Column: 1}
+	expected.ExpressionPosition = Position{Line: 5, Column: 13}
+
 	expectedYAML := fmt.Sprintf(
 		expectedYAMLFmt,
 		expected.Name,
@@ -0,0 +1,137 @@
+package authz
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+)
+
+// ReloadEvent is emitted after an attempt to reload the authorization
+// configuration of a `ReloadableEngine`.
+type ReloadEvent struct {
+	// Where the configuration was loaded from, e.g. a file path.
+	Source string
+
+	// When the reload was attempted.
+	Timestamp time.Time
+
+	// The reason the reload failed, nil on success.
+	Err error
+}
+
+// ReloadListener is a function that is called on reload events.
+type ReloadListener func(ReloadEvent)
+
+// ReloadableEngine is an authorization engine whose configuration can be
+// replaced at runtime. It is safe for concurrent use.
+//
+// A new configuration is fully compiled before being swapped in atomically.
+// If it fails to compile, the current one keeps being served.
+type ReloadableEngine struct {
+	current atomic.Pointer[Engine]
+
+	listenersMu sync.RWMutex
+	listeners   []ReloadListener
+}
+
+// NewReloadableEngine creates a new reloadable authorization engine.
+func NewReloadableEngine(config *AuthzConfig) (*ReloadableEngine, error) {
+	engine, err := NewEngine(config)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReloadableEngine{}
+	r.current.Store(engine)
+
+	return r, nil
+}
+
+// Engine returns the engine currently in use.
+func (r *ReloadableEngine) Engine() *Engine {
+	return r.current.Load()
+}
+
+// Config returns the configuration currently in use.
+func (r *ReloadableEngine) Config() *AuthzConfig {
+	return r.Engine().config
+}
+
+// Authorize evaluates a policy for a path, using the current engine.
+func (r *ReloadableEngine) Authorize(
+	path string,
+	claims *recloak.Claims,
+	request any,
+) error {
+	return r.Engine().Authorize(path, claims, request)
+}
+
+// Decide evaluates a policy for a path, using the current engine.
+func (r *ReloadableEngine) Decide(
+	path string,
+	claims *recloak.Claims,
+	request any,
+) Decision {
+	return r.Engine().Decide(path, claims, request)
+}
+
+// OnReload registers a listener that is called after every reload attempt.
+func (r *ReloadableEngine) OnReload(listener ReloadListener) {
+	r.listenersMu.Lock()
+	defer r.listenersMu.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// Reload compiles the given configuration and swaps it in on success.
+func (r *ReloadableEngine) Reload(config *AuthzConfig) error {
+	return r.ReloadFrom("api", func() (*AuthzConfig, error) {
+		return config, nil
+	})
+}
+
+// ReloadFrom loads a configuration using the given loader, compiles it and
+// swaps it in on success. The source is reported in the emitted event.
+func (r *ReloadableEngine) ReloadFrom(
+	source string,
+	load func() (*AuthzConfig, error),
+) error {
+	event := ReloadEvent{Source: source, Timestamp: time.Now()}
+
+	config, err := load()
+	if err == nil {
+		var engine *Engine
+		if engine, err = NewEngine(config); err == nil {
+			r.current.Store(engine)
+		}
+	}
+
+	event.Err = err
+	r.emit(event)
+
+	return err
+}
+
+func (r *ReloadableEngine) emit(event ReloadEvent) {
+	if event.Err != nil {
+		log.Error().
+			Err(event.Err).
+			Str("source", event.Source).
+			Msg("could not reload authorization configuration")
+	} else {
+		log.Info().
+			Str("source", event.Source).
+			Msg("reloaded authorization configuration")
+	}
+
+	r.listenersMu.RLock()
+	defer r.listenersMu.RUnlock()
+
+	for _, listener := range r.listeners {
+		listener(event)
+	}
+}
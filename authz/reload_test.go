@@ -0,0 +1,71 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadableEngine(t *testing.T) {
+	newConfig := func(expression string) *AuthzConfig {
+		return &AuthzConfig{
+			PathSeparator:   ".",
+			EnforcementMode: EnforcementModeEnforcing,
+			Resources: []Resource{
+				{
+					Name: "resource",
+					Policy: &PolicySpec{
+						InPlace: &Policy{Expression: expression},
+					},
+				},
+			},
+		}
+	}
+
+	engine, err := NewReloadableEngine(newConfig("false"))
+	require.NoError(t, err)
+
+	var events []ReloadEvent
+	engine.OnReload(func(event ReloadEvent) {
+		events = append(events, event)
+	})
+
+	require.ErrorIs(t, engine.Authorize("resource", nil, nil), ErrUnauthorized)
+
+	t.Run("successful reload", func(t *testing.T) {
+		err := engine.Reload(newConfig("true"))
+		require.NoError(t, err)
+		require.NoError(t, engine.Authorize("resource", nil, nil))
+
+		require.Len(t, events, 1)
+		require.Equal(t, "api", events[0].Source)
+		require.NoError(t, events[0].Err)
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		previous := engine.Engine()
+
+		err := engine.Reload(newConfig("true &&"))
+		require.Error(t, err)
+		require.Same(t, previous, engine.Engine())
+		require.NoError(t, engine.Authorize("resource", nil, nil))
+
+		require.Len(t, events, 2)
+		require.Equal(t, err, events[1].Err)
+	})
+
+	t.Run("failed load", func(t *testing.T) {
+		expected := errors.New("load failed")
+
+		err := engine.ReloadFrom("file.yaml", func() (*AuthzConfig, error) {
+			return nil, expected
+		})
+		require.ErrorIs(t, err, expected)
+		require.NoError(t, engine.Authorize("resource", nil, nil))
+
+		require.Len(t, events, 3)
+		require.Equal(t, "file.yaml", events[2].Source)
+		require.ErrorIs(t, events[2].Err, expected)
+	})
+}
@@ -0,0 +1,148 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+
+	"github.com/real-evolution/recloak"
+)
+
+// ErrRemoteDecision is returned when keycloak could not make a decision. It is
+// an `ErrUnavailable` error.
+var ErrRemoteDecision = fmt.Errorf("%w: could not get decision from keycloak", ErrUnavailable)
+
+// decideFrom makes a decision for a path using the configured decision
+// source.
+func (e *Enforcer) decideFrom(
+	ctx context.Context,
+	engine *Engine,
+	accessToken string,
+	claims *recloak.Claims,
+	path string,
+	request any,
+) Decision {
+	switch engine.config.DecisionSource {
+	case DecisionSourceRemote:
+		return engine.decideRemote(path, e.remoteCheck(ctx, engine.config, accessToken))
+
+	case DecisionSourceBoth:
+		// resources that are not mapped to keycloak permissions are decided
+		// by their local policies only
+		local := engine.Decide(path, claims, request)
+		if !local.Allowed || !engine.isMapped(path) {
+			return local
+		}
+
+		remote := engine.decideRemote(path, e.remoteCheck(ctx, engine.config, accessToken))
+		remote.Expression = local.Expression
+		remote.Clauses = local.Clauses
+		remote.FailedClause = local.FailedClause
+		remote.Timestamp = local.Timestamp
+		remote.Duration = time.Since(local.Timestamp)
+
+		// a request denied locally in shadow mode is still checked remotely, so
+		// that both results are reported
+		if local.Shadowed {
+			remote.Shadowed = true
+			remote.Err = errors.Join(local.Err, remote.Err)
+		}
+
+		return remote
+
+	default:
+		return engine.Decide(path, claims, request)
+	}
+}
+
+// remoteCheck returns a check of keycloak permissions that asks keycloak
+// authorization services whether the owner of the access token is granted
+// them, using the UMA grant.
+func (e *Enforcer) remoteCheck(
+	ctx context.Context,
+	config *AuthzConfig,
+	accessToken string,
+) func(permissions []string) error {
+	return func(permissions []string) error {
+		clientConfig := e.client.Config()
+		options := gocloak.RequestingPartyTokenOptions{
+			Audience:    gocloak.StringP(clientConfig.ClientID),
+			Permissions: &permissions,
+		}
+
+		var granted bool
+		var err error
+
+		switch config.RemoteResponseMode {
+		case RemoteResponseModePermissions:
+			var result *[]gocloak.RequestingPartyPermission
+			result, err = e.client.Client().GetRequestingPartyPermissions(
+				ctx,
+				accessToken,
+				clientConfig.Realm,
+				options,
+			)
+			granted = err == nil && isGranted(permissions, *result)
+
+		default:
+			var result *gocloak.RequestingPartyPermissionDecision
+			result, err = e.client.Client().GetRequestingPartyPermissionDecision(
+				ctx,
+				accessToken,
+				clientConfig.Realm,
+				options,
+			)
+			granted = err == nil && result.Result != nil && *result.Result
+		}
+
+		if err != nil && !isAccessDenied(err) {
+			return fmt.Errorf("%w: %w", ErrRemoteDecision, err)
+		}
+
+		if !granted {
+			return ErrUnauthorized
+		}
+
+		return nil
+	}
+}
+
+// isGranted checks whether all requested permissions, as `resource#scope`, are
+// among the granted ones.
+func isGranted(
+	requested []string,
+	granted []gocloak.RequestingPartyPermission,
+) bool {
+	for _, permission := range requested {
+		resource, scope, hasScope := strings.Cut(permission, "#")
+
+		ok := slices.ContainsFunc(granted, func(p gocloak.RequestingPartyPermission) bool {
+			if gocloak.PString(p.ResourceName) != resource &&
+				gocloak.PString(p.ResourceID) != resource {
+				return false
+			}
+
+			return !hasScope ||
+				(p.Scopes != nil && slices.Contains(*p.Scopes, scope))
+		})
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isAccessDenied checks whether keycloak denied the requested permissions,
+// which it reports with a 403 response.
+func isAccessDenied(err error) bool {
+	var apiErr *gocloak.APIError
+
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
@@ -0,0 +1,186 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak"
+)
+
+// newTestUMAServer creates a fake keycloak token endpoint that grants the
+// given permissions.
+func newTestUMAServer(t *testing.T, granted map[string][]string) *recloak.ReCloak {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		"/realms/test/protocol/openid-connect/token",
+		func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "urn:ietf:params:oauth:grant-type:uma-ticket", r.PostForm.Get("grant_type"))
+			require.Equal(t, "client", r.PostForm.Get("audience"))
+
+			w.Header().Set("Content-Type", "application/json")
+
+			allowed := true
+			for _, permission := range r.PostForm["permission"] {
+				resource, scope, _ := strings.Cut(permission, "#")
+				scopes, ok := granted[resource]
+				allowed = allowed && ok && (scope == "" || slices.Contains(scopes, scope))
+			}
+
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "access_denied"})
+				return
+			}
+
+			if r.PostForm.Get("response_mode") == "decision" {
+				_ = json.NewEncoder(w).Encode(map[string]bool{"result": true})
+				return
+			}
+
+			var permissions []map[string]any
+			for resource, scopes := range granted {
+				permissions = append(permissions, map[string]any{
+					"rsname": resource,
+					"scopes": scopes,
+				})
+			}
+			_ = json.NewEncoder(w).Encode(permissions)
+		},
+	)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := recloak.NewClient(&recloak.ClientConfig{
+		AuthServerURL: server.URL,
+		Realm:         "test",
+		ClientID:      "client",
+		ClientSecret:  "secret",
+	})
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestRemoteDecision(t *testing.T) {
+	client := newTestUMAServer(t, map[string][]string{"orders": {"read"}})
+
+	config := AuthzConfig{
+		PathSeparator:  ".",
+		DecisionSource: DecisionSourceBoth,
+		Resources: []Resource{
+			{
+				Name:     "orders",
+				Keycloak: &KeycloakPermission{Resource: "orders"},
+				Policy:   &PolicySpec{InPlace: &Policy{Expression: "true"}},
+				Children: []Resource{
+					{
+						Name:     "get",
+						Keycloak: &KeycloakPermission{Scopes: []string{"read"}},
+					},
+					{
+						Name:     "delete",
+						Keycloak: &KeycloakPermission{Scopes: []string{"delete"}},
+					},
+					{
+						Name:     "local",
+						Keycloak: &KeycloakPermission{Scopes: []string{"read"}},
+						Policy:   &PolicySpec{InPlace: &Policy{Expression: "false"}},
+					},
+				},
+			},
+			{
+				Name:   "unmapped",
+				Policy: &PolicySpec{InPlace: &Policy{Expression: "true"}},
+			},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	enforcer := &Enforcer{client: client}
+
+	for _, mode := range []RemoteResponseMode{
+		RemoteResponseModeDecision,
+		RemoteResponseModePermissions,
+	} {
+		t.Run(mode.String(), func(t *testing.T) {
+			config.RemoteResponseMode = mode
+
+			decide := func(path string) Decision {
+				return enforcer.decideFrom(context.Background(), engine, "token", nil, path, nil)
+			}
+
+			decision := decide("orders.get")
+			require.True(t, decision.Allowed)
+			require.Equal(t, []string{"orders#read"}, decision.Permissions)
+
+			decision = decide("orders.delete")
+			require.False(t, decision.Allowed)
+			require.ErrorIs(t, decision.Err, ErrUnauthorized)
+
+			decision = decide("orders.local")
+			require.False(t, decision.Allowed)
+			require.NotNil(t, decision.FailedClause)
+			require.Empty(t, decision.Permissions)
+
+			decision = decide("unmapped")
+			require.True(t, decision.Allowed)
+			require.Empty(t, decision.Permissions)
+		})
+	}
+
+	t.Run("shadow", func(t *testing.T) {
+		engine.SetEnforcementMode(EnforcementModeShadow)
+		defer engine.SetEnforcementMode(EnforcementModeEnforcing)
+
+		decide := func(path string) Decision {
+			return enforcer.decideFrom(context.Background(), engine, "token", nil, path, nil)
+		}
+
+		decision := decide("orders.local")
+		require.True(t, decision.Allowed)
+		require.True(t, decision.Shadowed)
+		require.ErrorIs(t, decision.Err, ErrUnauthorized)
+		require.NotNil(t, decision.FailedClause)
+		require.Equal(t, []string{"orders#read"}, decision.Permissions)
+
+		decision = decide("orders.delete")
+		require.True(t, decision.Allowed)
+		require.True(t, decision.Shadowed)
+		require.ErrorIs(t, decision.Err, ErrUnauthorized)
+		require.Equal(t, []string{"orders#delete"}, decision.Permissions)
+	})
+
+	t.Run("remote only", func(t *testing.T) {
+		config.DecisionSource = DecisionSourceRemote
+
+		decision := enforcer.decideFrom(context.Background(), engine, "token", nil, "orders.local", nil)
+		require.True(t, decision.Allowed)
+	})
+}
+
+func TestIsGranted(t *testing.T) {
+	granted := []gocloak.RequestingPartyPermission{
+		{ResourceName: gocloak.StringP("orders"), Scopes: &[]string{"read", "write"}},
+		{ResourceID: gocloak.StringP("1234")},
+	}
+
+	require.True(t, isGranted([]string{"orders#read", "orders#write"}, granted))
+	require.True(t, isGranted([]string{"orders", "1234"}, granted))
+	require.False(t, isGranted([]string{"orders#delete"}, granted))
+	require.False(t, isGranted([]string{"1234#read"}, granted))
+	require.False(t, isGranted([]string{"invoices"}, granted))
+}
@@ -1,5 +1,7 @@
 package authz
 
+import "gopkg.in/yaml.v3"
+
 // Resource is a resource that the access to which is controlled by an
 // authorization policy.
 type Resource struct {
@@ -12,6 +14,90 @@ type Resource struct {
 	// The description of the resource.
 	Policy *PolicySpec `yaml:"policy,omitempty"`
 
+	// The enforcement mode of the resource and its children, which defaults
+	// to the one of the parent resource, or the global one.
+	EnforcementMode *EnforcementMode `yaml:"enforcementMode,omitempty"`
+
+	// The messages to authorize individually when the resource is a
+	// streaming call, which defaults to the ones of the parent resource. Each
+	// message is passed as the request to the policy of the resource, which
+	// is not evaluated when the stream opens.
+	StreamDirection *StreamDirection `yaml:"streamDirection,omitempty"`
+
+	// The keycloak resource and scopes that the resource maps to, used for
+	// remote decisions. The keycloak resource defaults to the one of the
+	// parent resource.
+	Keycloak *KeycloakPermission `yaml:"keycloak,omitempty"`
+
 	// The description of the resource.
 	Children []Resource `yaml:"children,omitempty"`
+
+	// The location of the resource in the YAML source, if decoded from one.
+	Position Position `yaml:"-"`
+}
+
+func (r *Resource) UnmarshalYAML(value *yaml.Node) error {
+	type rawResource Resource
+	if err := value.Decode((*rawResource)(r)); err != nil {
+		return err
+	}
+
+	r.Position = positionOf(value)
+
+	return nil
+}
+
+// ProtectedResource is a resource of an engine, with its effective policy and
+// keycloak permission.
+type ProtectedResource struct {
+	// The full path of the resource.
+	Path string
+
+	// The display name of the resource.
+	DisplayName string
+
+	// The effective expression of the resource, empty if it has no policy.
+	Expression string
+
+	// The clauses of the effective expression.
+	Clauses []Clause
+
+	// The effective keycloak permission of the resource, if any.
+	Keycloak *KeycloakPermission
+}
+
+// KeycloakPermission is a keycloak resource, and optionally some of its scopes,
+// that access is requested to.
+type KeycloakPermission struct {
+	// The name or ID of the keycloak resource.
+	Resource string `yaml:"resource,omitempty"`
+
+	// The requested scopes of the resource, all of which must be granted.
+	Scopes []string `yaml:"scopes,omitempty"`
+}
+
+// inherit returns the permission with the resource of the given parent
+// permission if it has none.
+func (p *KeycloakPermission) inherit(parent *KeycloakPermission) *KeycloakPermission {
+	inherited := *p
+	if inherited.Resource == "" && parent != nil {
+		inherited.Resource = parent.Resource
+	}
+
+	return &inherited
+}
+
+// permissions returns the permission as `resource#scope` strings, as expected
+// by keycloak, one per scope.
+func (p *KeycloakPermission) permissions() []string {
+	if len(p.Scopes) == 0 {
+		return []string{p.Resource}
+	}
+
+	permissions := make([]string, len(p.Scopes))
+	for i, scope := range p.Scopes {
+		permissions[i] = p.Resource + "#" + scope
+	}
+
+	return permissions
 }
@@ -0,0 +1,133 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultRevocationRetention is how long revocations are kept by the in-memory
+// revocation store, if not configured. It must exceed the lifetime of access
+// tokens.
+const DefaultRevocationRetention = time.Hour
+
+// ErrTokenRevoked is returned when the session or the subject of a token was
+// revoked after the token was issued.
+var ErrTokenRevoked = errors.New("token was revoked")
+
+// RevocationStore is a type that records revoked sessions and subjects.
+type RevocationStore interface {
+	// RevokeSession revokes the tokens of a session issued until the given
+	// time.
+	RevokeSession(ctx context.Context, sessionID string, at time.Time) error
+
+	// RevokeSubject revokes the tokens of a subject issued until the given
+	// time.
+	RevokeSubject(ctx context.Context, subject string, at time.Time) error
+
+	// IsRevoked checks whether a token of the given session and subject,
+	// issued at the given time, was revoked. Either ID may be empty.
+	IsRevoked(
+		ctx context.Context,
+		sessionID string,
+		subject string,
+		issuedAt time.Time,
+	) (bool, error)
+}
+
+// MemoryRevocationStore is an in-memory revocation store that keeps
+// revocations for a retention period. It is safe for concurrent use.
+type MemoryRevocationStore struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu        sync.RWMutex
+	sessions  map[string]time.Time
+	subjects  map[string]time.Time
+	lastPurge time.Time
+}
+
+// NewMemoryRevocationStore creates a new in-memory revocation store that keeps
+// revocations for the given retention period, or the default one if zero.
+func NewMemoryRevocationStore(retention time.Duration) *MemoryRevocationStore {
+	if retention <= 0 {
+		retention = DefaultRevocationRetention
+	}
+
+	return &MemoryRevocationStore{
+		retention: retention,
+		now:       time.Now,
+		sessions:  make(map[string]time.Time),
+		subjects:  make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryRevocationStore) RevokeSession(
+	_ context.Context,
+	sessionID string,
+	at time.Time,
+) error {
+	s.revoke(s.sessions, sessionID, at)
+
+	return nil
+}
+
+func (s *MemoryRevocationStore) RevokeSubject(
+	_ context.Context,
+	subject string,
+	at time.Time,
+) error {
+	s.revoke(s.subjects, subject, at)
+
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(
+	_ context.Context,
+	sessionID string,
+	subject string,
+	issuedAt time.Time,
+) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return isRevokedIn(s.sessions, sessionID, issuedAt) ||
+		isRevokedIn(s.subjects, subject, issuedAt), nil
+}
+
+func (s *MemoryRevocationStore) revoke(ids map[string]time.Time, id string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := ids[id]; !ok || at.After(current) {
+		ids[id] = at
+	}
+
+	// purge expired revocations at most once per retention period
+	now := s.now()
+	if now.Sub(s.lastPurge) < s.retention {
+		return
+	}
+
+	s.lastPurge = now
+	for _, revocations := range []map[string]time.Time{s.sessions, s.subjects} {
+		for id, revokedAt := range revocations {
+			if now.Sub(revokedAt) > s.retention {
+				delete(revocations, id)
+			}
+		}
+	}
+}
+
+// isRevokedIn checks whether the given ID was revoked at or after the given
+// time.
+func isRevokedIn(revocations map[string]time.Time, id string, issuedAt time.Time) bool {
+	if id == "" {
+		return false
+	}
+
+	revokedAt, ok := revocations[id]
+
+	return ok && !issuedAt.After(revokedAt)
+}
@@ -0,0 +1,47 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	store := NewMemoryRevocationStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.RevokeSession(ctx, "session", now))
+	require.NoError(t, store.RevokeSubject(ctx, "user", now))
+
+	isRevoked := func(sessionID, subject string, issuedAt time.Time) bool {
+		revoked, err := store.IsRevoked(ctx, sessionID, subject, issuedAt)
+		require.NoError(t, err)
+
+		return revoked
+	}
+
+	require.True(t, isRevoked("session", "", now.Add(-time.Minute)))
+	require.True(t, isRevoked("session", "", now))
+	require.False(t, isRevoked("session", "", now.Add(time.Second)))
+	require.True(t, isRevoked("other", "user", now.Add(-time.Minute)))
+	require.False(t, isRevoked("other", "other", now.Add(-time.Minute)))
+	require.False(t, isRevoked("", "", now.Add(-time.Minute)))
+
+	t.Run("keeps latest revocation", func(t *testing.T) {
+		require.NoError(t, store.RevokeSession(ctx, "session", now.Add(-time.Hour)))
+		require.True(t, isRevoked("session", "", now))
+	})
+
+	t.Run("purges expired revocations", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		require.NoError(t, store.RevokeSession(ctx, "fresh", now))
+
+		require.Len(t, store.sessions, 1)
+		require.Empty(t, store.subjects)
+	})
+}
@@ -0,0 +1,165 @@
+package authz
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/expr-lang/expr/ast"
+)
+
+// ClientRole is a struct that declares a client role needed by the policies
+// of a service.
+type ClientRole struct {
+	// The name of the role.
+	Name string `yaml:"name"`
+
+	// The description of the role.
+	Description string `yaml:"description,omitempty"`
+
+	// The names of the declared client roles that the role is composed of.
+	Composites []string `yaml:"composites,omitempty"`
+}
+
+// RoleReferences is a set of the roles that policy expressions check by
+// literal names, through `InRole` and `InRealmRole`.
+type RoleReferences struct {
+	// The names of the client roles, sorted.
+	ClientRoles []string
+
+	// The names of the realm roles, sorted.
+	RealmRoles []string
+}
+
+// RoleReferences returns the roles checked by the policy, found by statically
+// scanning its expression. Roles given by non-literal expressions are not
+// included.
+func (p CompiledPolicy) RoleReferences() RoleReferences {
+	var scanner roleScanner
+	scanner.scan(p)
+
+	return scanner.references()
+}
+
+// RoleReferences returns the roles checked by the policies of the engine: the
+// policies of all resources, including instantiated templates, and all named
+// policies, whether they are used by resources or not.
+func (e *Engine) RoleReferences() RoleReferences {
+	var scanner roleScanner
+	for _, resource := range e.resources {
+		if resource.hasPolicy {
+			scanner.scan(resource.policy)
+		}
+	}
+
+	for _, name := range e.rawPolicies.resolved {
+		if !e.rawPolicies.IsValid(name) {
+			continue
+		}
+
+		// roles given by template parameters are not known until the
+		// template is instantiated by a resource
+		if policy, err := e.rawPolicies.compile(name); err == nil {
+			scanner.scan(policy)
+		}
+	}
+
+	return scanner.references()
+}
+
+// UndeclaredRoles returns the client roles that are checked by the policies of
+// the engine, but not declared in its configuration.
+func (e *Engine) UndeclaredRoles() []string {
+	var undeclared []string
+	for _, role := range e.RoleReferences().ClientRoles {
+		if !slices.ContainsFunc(e.config.Roles, func(declared ClientRole) bool {
+			return declared.Name == role
+		}) {
+			undeclared = append(undeclared, role)
+		}
+	}
+
+	return undeclared
+}
+
+// validateRoles checks that the declared roles are named, unique, and only
+// composed of declared roles.
+func validateRoles(roles []ClientRole) error {
+	names := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		if role.Name == "" {
+			return fmt.Errorf("role name is empty")
+		}
+
+		if _, ok := names[role.Name]; ok {
+			return fmt.Errorf("duplicate role name: %s", role.Name)
+		}
+
+		names[role.Name] = struct{}{}
+	}
+
+	for _, role := range roles {
+		for _, composite := range role.Composites {
+			if _, ok := names[composite]; !ok {
+				return fmt.Errorf("composite `%s` of role `%s` is not declared", composite, role.Name)
+			}
+
+			if composite == role.Name {
+				return fmt.Errorf("role `%s` is a composite of itself", role.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// roleScanner is an expression visitor that collects the literal arguments of
+// role checks.
+type roleScanner struct {
+	clientRoles []string
+	realmRoles  []string
+}
+
+// scan collects the role checks of the given policy.
+func (s *roleScanner) scan(policy CompiledPolicy) {
+	if policy.program == nil {
+		return
+	}
+
+	node := policy.program.Node()
+	ast.Walk(&node, s)
+}
+
+func (s *roleScanner) Visit(node *ast.Node) {
+	call, ok := (*node).(*ast.CallNode)
+	if !ok || len(call.Arguments) != 1 {
+		return
+	}
+
+	callee, ok := call.Callee.(*ast.IdentifierNode)
+	if !ok {
+		return
+	}
+
+	role, ok := call.Arguments[0].(*ast.StringNode)
+	if !ok || role.Value == "" {
+		return
+	}
+
+	switch callee.Value {
+	case "InRole":
+		s.clientRoles = append(s.clientRoles, role.Value)
+
+	case "InRealmRole":
+		s.realmRoles = append(s.realmRoles, role.Value)
+	}
+}
+
+func (s *roleScanner) references() RoleReferences {
+	slices.Sort(s.clientRoles)
+	slices.Sort(s.realmRoles)
+
+	return RoleReferences{
+		ClientRoles: slices.Compact(s.clientRoles),
+		RealmRoles:  slices.Compact(s.realmRoles),
+	}
+}
@@ -0,0 +1,70 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleReferences(t *testing.T) {
+	config := AuthzConfig{
+		PathSeparator: ".",
+		Policies: []Policy{
+			{Name: "is_reader", Expression: `InRole("orders:read") || InRealmRole("admin")`},
+			{Name: "is_auditor", Expression: `InRealmRole("auditor")`},
+			{
+				Name:       "has_role",
+				Parameters: []PolicyParameter{{Name: "role"}},
+				Expression: `InRole($role) || InRole("support")`,
+			},
+		},
+		Resources: []Resource{
+			{
+				Name:   "orders",
+				Policy: &PolicySpec{Ref: "is_reader"},
+				Children: []Resource{
+					{
+						Name: "update",
+						Policy: &PolicySpec{InPlace: &Policy{
+							Expression: `InRole("orders:write") && InRole(Params.role) && InRealmRole("admin")`,
+						}},
+					},
+				},
+			},
+			{
+				Name:   "refunds",
+				Policy: &PolicySpec{Ref: "has_role", Args: map[string]any{"role": "refunds:issue"}},
+			},
+			{Name: "health"},
+		},
+		Roles: []ClientRole{
+			{Name: "orders:read"},
+		},
+	}
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	require.Equal(t, RoleReferences{
+		ClientRoles: []string{"orders:read", "orders:write", "refunds:issue", "support"},
+		RealmRoles:  []string{"admin", "auditor"},
+	}, engine.RoleReferences())
+	require.Equal(t, []string{"orders:write", "refunds:issue", "support"}, engine.UndeclaredRoles())
+
+	policy, err := CompilePolicy(`InRealmRole("user")`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user"}, policy.RoleReferences().RealmRoles)
+	require.Empty(t, policy.RoleReferences().ClientRoles)
+}
+
+func TestValidateRoles(t *testing.T) {
+	require.NoError(t, validateRoles([]ClientRole{
+		{Name: "admin", Composites: []string{"user"}},
+		{Name: "user"},
+	}))
+
+	require.Error(t, validateRoles([]ClientRole{{}}))
+	require.Error(t, validateRoles([]ClientRole{{Name: "user"}, {Name: "user"}}))
+	require.Error(t, validateRoles([]ClientRole{{Name: "admin", Composites: []string{"user"}}}))
+	require.Error(t, validateRoles([]ClientRole{{Name: "admin", Composites: []string{"admin"}}}))
+}
@@ -0,0 +1,297 @@
+package authz
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyParameterPrefix is the prefix that indicates a reference to a
+// parameter of a policy template.
+const PolicyParameterPrefix = '$'
+
+// reservedParameterNames are the names that cannot be used for parameters,
+// as they are already defined by expr.
+var reservedParameterNames = []string{"env"}
+
+var parameterNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ParameterType is an enum that represents the type of a parameter of a
+// policy template.
+type ParameterType int
+
+const (
+	// ParameterTypeString is the parameter type of string arguments.
+	ParameterTypeString ParameterType = iota
+
+	// ParameterTypeInt is the parameter type of integer arguments.
+	ParameterTypeInt
+
+	// ParameterTypeFloat is the parameter type of floating point arguments,
+	// which also accepts integers.
+	ParameterTypeFloat
+
+	// ParameterTypeBool is the parameter type of boolean arguments.
+	ParameterTypeBool
+)
+
+// PolicyParameter is a named parameter of a policy template, referenced in
+// its expression as `$name`. Policies that include a template must declare
+// its parameters too, which are then passed through.
+type PolicyParameter struct {
+	// The name of the parameter.
+	Name string `yaml:"name"`
+
+	// The description of the parameter.
+	Description string `yaml:"description,omitempty"`
+
+	// The type of the arguments of the parameter, which defaults to string.
+	Type ParameterType `yaml:"type,omitempty"`
+}
+
+// parseParameterType parses parameter type from a string.
+func parseParameterType(typeStr string) (ParameterType, error) {
+	switch strings.ToLower(typeStr) {
+	case "string":
+		return ParameterTypeString, nil
+
+	case "int":
+		return ParameterTypeInt, nil
+
+	case "float":
+		return ParameterTypeFloat, nil
+
+	case "bool":
+		return ParameterTypeBool, nil
+
+	default:
+		return ParameterTypeString, fmt.Errorf(
+			"invalid parameter type: %s",
+			typeStr,
+		)
+	}
+}
+
+func (s *ParameterType) UnmarshalYAML(value *yaml.Node) (err error) {
+	*s, err = parseParameterType(value.Value)
+
+	return
+}
+
+func (s ParameterType) String() string {
+	switch s {
+	case ParameterTypeString:
+		return "string"
+
+	case ParameterTypeInt:
+		return "int"
+
+	case ParameterTypeFloat:
+		return "float"
+
+	case ParameterTypeBool:
+		return "bool"
+
+	default:
+		return "unknown"
+	}
+}
+
+// literal returns the expr literal of the given argument, if it is of the
+// type.
+func (s ParameterType) literal(arg any) (string, error) {
+	switch s {
+	case ParameterTypeString:
+		if value, ok := arg.(string); ok {
+			return strconv.Quote(value), nil
+		}
+
+	case ParameterTypeInt:
+		switch value := arg.(type) {
+		case int:
+			return strconv.Itoa(value), nil
+
+		case int64:
+			return strconv.FormatInt(value, 10), nil
+
+		case uint64:
+			return strconv.FormatUint(value, 10), nil
+		}
+
+	case ParameterTypeFloat:
+		var value float64
+		switch arg := arg.(type) {
+		case int:
+			value = float64(arg)
+
+		case float64:
+			value = arg
+
+		default:
+			return "", fmt.Errorf("expected %s, got %v", s, arg)
+		}
+
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return "", fmt.Errorf("expected finite %s, got %v", s, value)
+		}
+
+		literal := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".e") {
+			literal += ".0"
+		}
+
+		return literal, nil
+
+	case ParameterTypeBool:
+		if value, ok := arg.(bool); ok {
+			return strconv.FormatBool(value), nil
+		}
+	}
+
+	return "", fmt.Errorf("expected %s, got %v", s, arg)
+}
+
+// zero returns the expr literal of the zero value of the type, used to check
+// templates before they are instantiated.
+func (s ParameterType) zero() string {
+	switch s {
+	case ParameterTypeInt:
+		return "0"
+
+	case ParameterTypeFloat:
+		return "0.0"
+
+	case ParameterTypeBool:
+		return "false"
+
+	default:
+		return `""`
+	}
+}
+
+// IsTemplate checks whether the policy declares parameters, and can only be
+// referenced with arguments.
+func (p *Policy) IsTemplate() bool {
+	return len(p.Parameters) > 0
+}
+
+// Instantiate returns the named policy of the set, with its includes expanded
+// and its parameters substituted with the given arguments.
+func (p *PolicyMap) Instantiate(name string, args map[string]any) (Policy, error) {
+	policy, ok := p.Get(name)
+	if !ok {
+		return Policy{}, fmt.Errorf("policy %s not found", name)
+	}
+
+	literals, err := policy.bindArguments(args)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	policy.Expression, err = substituteParameters(policy, literals)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return policy, nil
+}
+
+// validateParameters checks that the parameters of the policy are well-formed
+// and unique.
+func (p *Policy) validateParameters() error {
+	for i, param := range p.Parameters {
+		if !parameterNamePattern.MatchString(param.Name) ||
+			slices.Contains(reservedParameterNames, param.Name) {
+			return fmt.Errorf("invalid parameter name: %s", param.Name)
+		}
+
+		if slices.ContainsFunc(p.Parameters[:i], func(other PolicyParameter) bool {
+			return other.Name == param.Name
+		}) {
+			return fmt.Errorf("duplicate parameter name: %s", param.Name)
+		}
+	}
+
+	return nil
+}
+
+// bindArguments returns the literals of the given arguments by parameter
+// name, checking that there is exactly one argument of the right type for
+// each parameter of the policy.
+func (p *Policy) bindArguments(args map[string]any) (map[string]string, error) {
+	if len(args) != len(p.Parameters) {
+		return nil, fmt.Errorf(
+			"policy %s takes %d arguments, got %d",
+			p.Name,
+			len(p.Parameters),
+			len(args),
+		)
+	}
+
+	literals := make(map[string]string, len(p.Parameters))
+	for _, param := range p.Parameters {
+		arg, ok := args[param.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing argument `%s` of policy %s", param.Name, p.Name)
+		}
+
+		literal, err := param.Type.literal(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument `%s` of policy %s: %w", param.Name, p.Name, err)
+		}
+
+		literals[param.Name] = literal
+	}
+
+	return literals, nil
+}
+
+// placeholders returns the literals of the zero values of the parameters of
+// the policy, by parameter name.
+func (p *Policy) placeholders() map[string]string {
+	literals := make(map[string]string, len(p.Parameters))
+	for _, param := range p.Parameters {
+		literals[param.Name] = param.Type.zero()
+	}
+
+	return literals
+}
+
+// substituteParameters replaces the parameter references of the expression of
+// the given policy with the given literals.
+func substituteParameters(policy Policy, literals map[string]string) (string, error) {
+	refs, err := scanParameters(policy.Expression)
+	if err != nil {
+		return "", err
+	}
+
+	for _, ref := range refs {
+		if _, ok := literals[ref.name]; !ok {
+			return "", fmt.Errorf("undeclared parameter `%s` in %s", ref.name, policy.Name)
+		}
+	}
+
+	return replaceReferences(policy.Expression, refs, func(name string) string {
+		return literals[name]
+	}), nil
+}
+
+// scanParameters returns the parameter references of the given expression,
+// ignoring quoted strings and the reserved names.
+func scanParameters(expr string) ([]reference, error) {
+	refs, err := scanReferences(expr, "parameter", PolicyParameterPrefix, func(byte) bool {
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(refs, func(ref reference) bool {
+		return slices.Contains(reservedParameterNames, ref.name)
+	}), nil
+}
@@ -0,0 +1,247 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDecodeTemplatePolicySpecFromYAML(t *testing.T) {
+	const specYAML = `{ref: has_role, args: {role: "orders:read", level: 2}}`
+
+	var spec PolicySpec
+	require.NoError(t, yaml.Unmarshal([]byte(specYAML), &spec))
+
+	require.Nil(t, spec.InPlace)
+	require.Equal(t, "has_role", spec.Ref)
+	require.Equal(t, map[string]any{"role": "orders:read", "level": 2}, spec.Args)
+}
+
+func TestEngineTemplates(t *testing.T) {
+	type testRequest struct {
+		Name  string
+		Level int
+		Ratio float64
+	}
+
+	const configYAML = `
+pathSeparator: "."
+policies:
+  - name: named
+    parameters:
+      - name: name
+    expression: Request.Name == $name
+  - name: named_at_level
+    parameters:
+      - name: name
+      - name: level
+        type: int
+    expression: "@named && Request.Level >= $level && $env != nil"
+  - name: above
+    parameters:
+      - name: ratio
+        type: float
+    expression: Request.Ratio > $ratio
+  - name: quoted_named
+    parameters:
+      - name: name
+    expression: '"it''s $name" != Request.Name && Request.Name == $name'
+resources:
+  - name: foo
+    policy: {ref: named, args: {name: foo}}
+  - name: quoted
+    policy: {ref: named, args: {name: "a\" || true || \"b"}}
+  - name: admin
+    policy: {ref: named_at_level, args: {name: admin, level: 3}}
+  - name: half
+    policy: {ref: above, args: {ratio: 0.5}}
+  - name: one
+    policy: {ref: above, args: {ratio: 1}}
+  - name: mixed
+    policy: {ref: quoted_named, args: {name: "it's"}}
+`
+
+	var config AuthzConfig
+	require.NoError(t, yaml.Unmarshal([]byte(configYAML), &config))
+
+	engine, err := NewEngine(&config)
+	require.NoError(t, err)
+
+	cases := []struct {
+		path    string
+		request testRequest
+		allowed bool
+	}{
+		{"foo", testRequest{Name: "foo"}, true},
+		{"foo", testRequest{Name: "bar"}, false},
+		{"quoted", testRequest{Name: "bar"}, false},
+		{"quoted", testRequest{Name: `a" || true || "b`}, true},
+		{"admin", testRequest{Name: "admin", Level: 3}, true},
+		{"admin", testRequest{Name: "admin", Level: 2}, false},
+		{"admin", testRequest{Name: "foo", Level: 3}, false},
+		{"half", testRequest{Ratio: 0.75}, true},
+		{"half", testRequest{Ratio: 0.25}, false},
+		{"one", testRequest{Ratio: 1.5}, true},
+		{"mixed", testRequest{Name: "it's"}, true},
+		{"mixed", testRequest{Name: "it's $name"}, false},
+	}
+
+	for _, c := range cases {
+		err := engine.Authorize(c.path, nil, c.request)
+		if c.allowed {
+			require.NoError(t, err, "%s: %+v", c.path, c.request)
+		} else {
+			require.ErrorIs(t, err, ErrUnauthorized, "%s: %+v", c.path, c.request)
+		}
+	}
+
+	for _, resource := range engine.ProtectedResources() {
+		if resource.Path == "admin" {
+			require.Equal(
+				t,
+				`((Request.Name == "admin") && Request.Level >= 3 && $env != nil)`,
+				resource.Expression,
+			)
+		}
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	const configYAML = `
+pathSeparator: "."
+policies:
+  - name: named
+    parameters:
+      - name: name
+    expression: Request.Name == $name
+  - name: undeclared
+    expression: "@named"
+  - name: reserved
+    parameters:
+      - name: env
+    expression: "true"
+  - name: mistyped
+    parameters:
+      - name: level
+        type: int
+    expression: InRole($level)
+resources:
+  - name: missing
+    policy: {ref: named, args: {other: foo}}
+  - name: extra
+    policy: {ref: named, args: {name: foo, other: bar}}
+  - name: wrong
+    policy: {ref: named, args: {name: 1}}
+  - name: plain
+    policy: {ref: mistyped_not_found, args: {name: 1}}
+  - name: bare
+    policy: named
+`
+
+	var config AuthzConfig
+	require.NoError(t, yaml.Unmarshal([]byte(configYAML), &config))
+
+	_, err := NewEngine(&config)
+
+	var errs ConfigErrors
+	require.True(t, errors.As(err, &errs))
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	require.Equal(t, []string{
+		"8:5: policy `undeclared`: undeclared parameter `name` in undeclared",
+		"10:5: policy `reserved`: invalid parameter name: env",
+		"18:17: policy `mistyped`: cannot use int as argument (type string) to call InRole",
+		"21:13: resource `missing`: missing argument `name` of policy named",
+		"23:13: resource `extra`: policy named takes 1 arguments, got 2",
+		"25:13: resource `wrong`: argument `name` of policy named: expected string, got 1",
+		"27:13: resource `plain`: policy mistyped_not_found not found",
+		"29:13: resource `bare`: policy named takes 1 arguments, got 0",
+	}, messages)
+}
+
+func TestParameterTypeLiteral(t *testing.T) {
+	cases := []struct {
+		typ     ParameterType
+		arg     any
+		literal string
+	}{
+		{ParameterTypeString, `it's "quoted"`, `"it's \"quoted\""`},
+		{ParameterTypeString, "line\nbreak", `"line\nbreak"`},
+		{ParameterTypeInt, 42, "42"},
+		{ParameterTypeInt, int64(-7), "-7"},
+		{ParameterTypeFloat, 2, "2.0"},
+		{ParameterTypeFloat, 0.25, "0.25"},
+		{ParameterTypeFloat, 1e21, "1e+21"},
+		{ParameterTypeBool, true, "true"},
+	}
+
+	for _, c := range cases {
+		literal, err := c.typ.literal(c.arg)
+		require.NoError(t, err)
+		require.Equal(t, c.literal, literal)
+
+		_, err = CompilePolicy(literal + " != nil")
+		require.NoError(t, err, literal)
+	}
+
+	for _, typ := range []ParameterType{
+		ParameterTypeString,
+		ParameterTypeInt,
+		ParameterTypeFloat,
+		ParameterTypeBool,
+	} {
+		_, err := typ.literal([]any{"a"})
+		require.Error(t, err)
+	}
+}
+
+func TestSubstituteParameters(t *testing.T) {
+	literals := map[string]string{"role": `"admin"`, "level": "3"}
+
+	testData := []struct {
+		expr     string
+		expected string
+	}{
+		{
+			expr:     `InRole($role) && Request.Level >= $level`,
+			expected: `InRole("admin") && Request.Level >= 3`,
+		},
+		{
+			expr:     `"it's $role" != "x" && InRole($role)`,
+			expected: `"it's $role" != "x" && InRole("admin")`,
+		},
+		{
+			expr:     `'say "$role' == 'x' || InRole($role)`,
+			expected: `'say "$role' == 'x' || InRole("admin")`,
+		},
+		{
+			expr:     `"a \"$role\" \\" == Request.Name || InRole($role)`,
+			expected: `"a \"$role\" \\" == Request.Name || InRole("admin")`,
+		},
+		{
+			expr:     "`raw $role \\` == Request.Name || $env != nil && InRole($role)",
+			expected: "`raw $role \\` == Request.Name || $env != nil && InRole(\"admin\")",
+		},
+	}
+
+	for _, data := range testData {
+		actual, err := substituteParameters(Policy{Name: "p", Expression: data.expr}, literals)
+		require.NoError(t, err, data.expr)
+		require.Equal(t, data.expected, actual)
+
+		_, err = CompilePolicy(actual)
+		require.NoError(t, err, actual)
+	}
+
+	_, err := substituteParameters(Policy{Name: "p", Expression: `'it\'s' == $other`}, literals)
+	require.EqualError(t, err, "undeclared parameter `other` in p")
+
+	_, err = substituteParameters(Policy{Name: "p", Expression: `"$role`}, literals)
+	require.Error(t, err)
+}
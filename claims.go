@@ -0,0 +1,119 @@
+package recloak
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Get returns the raw value of the claim with the given name. Nil claims
+// have no claims.
+func (c *Claims) Get(name string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	value, ok := c.Extra[name]
+
+	return value, ok
+}
+
+// GetString returns the claim with the given name as a string.
+func (c *Claims) GetString(name string) (string, bool) {
+	value, _ := c.Get(name)
+	str, ok := value.(string)
+
+	return str, ok
+}
+
+// GetStrings returns the claim with the given name as a list of strings. A
+// single string claim is returned as a list with one element.
+func (c *Claims) GetStrings(name string) ([]string, bool) {
+	raw, _ := c.Get(name)
+
+	switch value := raw.(type) {
+	case string:
+		return []string{value}, true
+
+	case []string:
+		return value, true
+
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			str, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+
+			values = append(values, str)
+		}
+
+		return values, true
+
+	default:
+		return nil, false
+	}
+}
+
+// GetBool returns the claim with the given name as a boolean.
+func (c *Claims) GetBool(name string) (bool, bool) {
+	value, _ := c.Get(name)
+	b, ok := value.(bool)
+
+	return b, ok
+}
+
+// GetNumber returns the claim with the given name as a number.
+func (c *Claims) GetNumber(name string) (float64, bool) {
+	raw, _ := c.Get(name)
+
+	switch value := raw.(type) {
+	case float64:
+		return value, true
+
+	case int:
+		return float64(value), true
+
+	case int64:
+		return float64(value), true
+
+	default:
+		return 0, false
+	}
+}
+
+// Scopes returns the scopes of the `scope` claim.
+func (c *Claims) Scopes() []string {
+	scope, _ := c.GetString("scope")
+
+	return strings.Fields(scope)
+}
+
+// HasScope checks if the token was granted the given scope.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+// decodeExtraClaims decodes all claims of the given token into a map.
+func decodeExtraClaims(token *jwt.Token) (map[string]any, error) {
+	segments := strings.Split(token.Raw, ".")
+	if len(segments) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	payload, err := jwt.NewParser().DecodeSegment(segments[1])
+	if err != nil {
+		return nil, err
+	}
+
+	extra := make(map[string]any)
+	if err := json.Unmarshal(payload, &extra); err != nil {
+		return nil, err
+	}
+
+	return extra, nil
+}
@@ -0,0 +1,109 @@
+package recloak
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeAccessTokenExtraClaims(t *testing.T) {
+	server := newTestRealmServer(t)
+	key := server.rotate(t, "first")
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	registered := server.claims()
+	raw := signTestToken(t, key, "first", jwt.MapClaims{
+		"iss":                registered.Issuer,
+		"sub":                registered.Subject,
+		"aud":                registered.Audience,
+		"exp":                registered.ExpiresAt,
+		"preferred_username": "username",
+		"tenant_id":          "acme",
+		"email_verified":     true,
+		"level":              3,
+		"groups":             []string{"/tenants/acme", "/admins"},
+		"scope":              "openid profile orders:read",
+	})
+
+	token, err := client.DecodeAccessToken(context.Background(), raw)
+	require.NoError(t, err)
+
+	claims := token.Claims
+	require.Equal(t, "username", claims.PreferredUsername)
+
+	sub, ok := claims.GetString("sub")
+	require.True(t, ok)
+	require.Equal(t, "user", sub)
+
+	tenantID, ok := claims.GetString("tenant_id")
+	require.True(t, ok)
+	require.Equal(t, "acme", tenantID)
+
+	_, ok = claims.GetString("missing")
+	require.False(t, ok)
+
+	verified, ok := claims.GetBool("email_verified")
+	require.True(t, ok)
+	require.True(t, verified)
+
+	level, ok := claims.GetNumber("level")
+	require.True(t, ok)
+	require.Equal(t, 3.0, level)
+
+	groups, ok := claims.GetStrings("groups")
+	require.True(t, ok)
+	require.Equal(t, []string{"/tenants/acme", "/admins"}, groups)
+
+	require.Equal(t, []string{"openid", "profile", "orders:read"}, claims.Scopes())
+	require.True(t, claims.HasScope("orders:read"))
+	require.False(t, claims.HasScope("orders:write"))
+}
+
+func TestDecodeAccessTokenAs(t *testing.T) {
+	type tenantClaims struct {
+		jwt.RegisteredClaims
+
+		TenantID string   `json:"tenant_id"`
+		Groups   []string `json:"groups"`
+	}
+
+	server := newTestRealmServer(t)
+	key := server.rotate(t, "first")
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	expected := tenantClaims{
+		RegisteredClaims: server.claims().RegisteredClaims,
+		TenantID:         "acme",
+		Groups:           []string{"/tenants/acme"},
+	}
+
+	token, claims, err := DecodeAccessTokenAs[tenantClaims](
+		context.Background(),
+		client,
+		signTestToken(t, key, "first", expected),
+	)
+	require.NoError(t, err)
+	require.True(t, token.Valid)
+	require.Equal(t, expected.TenantID, claims.TenantID)
+	require.Equal(t, expected.Groups, claims.Groups)
+	require.Equal(t, expected.Subject, claims.Subject)
+}
+
+func TestNilClaims(t *testing.T) {
+	var claims *Claims
+
+	_, ok := claims.Get("scope")
+	require.False(t, ok)
+
+	_, ok = claims.GetStrings("groups")
+	require.False(t, ok)
+
+	require.Empty(t, claims.Scopes())
+	require.False(t, claims.HasScope("openid"))
+}
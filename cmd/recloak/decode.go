@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/real-evolution/recloak"
+)
+
+func runDecode(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		return usageError(stderr, decodeUsage)
+	}
+
+	claims, err := decodeClaims(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(claims.Extra); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// decodeClaims decodes the claims of the given token without verifying it, the
+// same way they are decoded for policies.
+func decodeClaims(tokenString string) (*recloak.Claims, error) {
+	parser := jwt.NewParser()
+
+	var claims recloak.Claims
+	_, parts, err := parser.ParseUnverified(tokenString, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := parser.DecodeSegment(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(payload, &claims.Extra); err != nil {
+		return nil, fmt.Errorf("%w: %w", jwt.ErrTokenMalformed, err)
+	}
+
+	return &claims, nil
+}
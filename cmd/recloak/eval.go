@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/real-evolution/recloak"
+)
+
+func runEval(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	path := flags.String("path", "", "the requested resource path")
+	claimsPath := flags.String("claims", "", "a JSON file of the token claims")
+	requestPath := flags.String("request", "", "a JSON file of the request payload")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *path == "" {
+		return usageError(stderr, evalUsage)
+	}
+
+	engine, err := loadEngine(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	claims := &recloak.Claims{}
+	if *claimsPath != "" {
+		if claims, err = readClaims(*claimsPath); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	var request any
+	if *requestPath != "" {
+		if err := readJSON(*requestPath, &request); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	decision := engine.Decide(*path, claims, request)
+
+	fmt.Fprintln(stdout, decision.String())
+	if decision.Expression != "" {
+		fmt.Fprintf(stdout, "expression: %s\n", decision.Expression)
+	}
+
+	// denials exit with the same code as errors, so that scripts can check
+	// decisions
+	if !decision.Allowed {
+		return 1
+	}
+
+	return 0
+}
+
+// readClaims reads the claims of a token from a JSON file.
+func readClaims(path string) (*recloak.Claims, error) {
+	var claims recloak.Claims
+	if err := readJSON(path, &claims); err != nil {
+		return nil, err
+	}
+
+	if err := readJSON(path, &claims.Extra); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func readJSON(path string, v any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
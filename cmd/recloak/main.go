@@ -0,0 +1,93 @@
+// Command recloak validates, inspects and evaluates recloak authorization
+// configurations.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is a subcommand of the CLI.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+const (
+	validateUsage = "validate <config.yaml>"
+	treeUsage     = "tree <config.yaml>"
+	evalUsage     = "eval --path <path> [--claims claims.json] [--request req.json] <config.yaml>"
+	decodeUsage   = "decode <jwt>"
+)
+
+var commands = []command{
+	{
+		name:    "validate",
+		usage:   validateUsage,
+		summary: "check a configuration and report errors with their lines",
+		run:     runValidate,
+	},
+	{
+		name:    "tree",
+		usage:   treeUsage,
+		summary: "print the resource paths with their composed expressions",
+		run:     runTree,
+	},
+	{
+		name:    "eval",
+		usage:   evalUsage,
+		summary: "evaluate a request, print the decision and fail if denied",
+		run:     runEval,
+	},
+	{
+		name:    "decode",
+		usage:   decodeUsage,
+		summary: "print the claims of a token without verifying it",
+		run:     runDecode,
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the CLI with the given arguments, and returns its exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+
+	fmt.Fprintf(stderr, "unknown command: %s\n\n", args[0])
+	printUsage(stderr)
+
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: recloak <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+		fmt.Fprintf(w, "  %-10s recloak %s\n", "", cmd.usage)
+	}
+}
+
+// usageError prints the given usage of a command, and returns the exit code
+// of invalid invocations.
+func usageError(stderr io.Writer, usage string) int {
+	fmt.Fprintf(stderr, "usage: recloak %s\n", usage)
+
+	return 2
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestValidate(t *testing.T) {
+	code, stdout, _ := runCLI("validate", "testdata/config.yaml")
+	require.Equal(t, 0, code)
+	require.Equal(t, "testdata/config.yaml: ok\n", stdout)
+
+	code, _, stderr := runCLI("validate", "testdata/invalid.yaml")
+	require.Equal(t, 1, code)
+
+	require.Equal(t, ""+
+		"testdata/invalid.yaml:8:42: policy `is_reader`: unexpected token EOF\n"+
+		"testdata/invalid.yaml:9:7: policy `is_writer`: unresolved reference `missing` in is_writer\n"+
+		"testdata/invalid.yaml:13:15: resource `orders`: policy is_admin not found\n"+
+		"testdata/invalid.yaml:17:32: resource `orders.list`: unexpected end of expression\n",
+		stderr,
+	)
+
+	code, _, _ = runCLI("validate")
+	require.Equal(t, 2, code)
+}
+
+func TestTree(t *testing.T) {
+	code, stdout, _ := runCLI("tree", "testdata/config.yaml")
+	require.Equal(t, 0, code)
+	require.Equal(t, ""+
+		"orders       (InRole(\"orders:read\") || InRealmRole(\"admin\"))\n"+
+		"orders.get   ((InRole(\"orders:read\") || InRealmRole(\"admin\"))) && (Claim(\"tenant\") == Request.tenant)\n"+
+		"orders.list  (InRole(\"orders:read\") || InRealmRole(\"admin\"))\n",
+		stdout,
+	)
+}
+
+func TestEval(t *testing.T) {
+	code, stdout, _ := runCLI(
+		"eval",
+		"--path", "orders.get",
+		"--claims", "testdata/claims.json",
+		"--request", "testdata/request.json",
+		"testdata/config.yaml",
+	)
+	require.Equal(t, 1, code)
+	require.True(t, strings.HasPrefix(stdout, "denied access to `orders.get`"), stdout)
+	require.Contains(t, stdout, "failed: policy `same_tenant`")
+
+	code, stdout, _ = runCLI(
+		"eval",
+		"--path", "orders.list",
+		"--claims", "testdata/claims.json",
+		"testdata/config.yaml",
+	)
+	require.Equal(t, 0, code)
+	require.True(t, strings.HasPrefix(stdout, "allowed access to `orders.list`"), stdout)
+
+	code, _, _ = runCLI("eval", "testdata/config.yaml")
+	require.Equal(t, 2, code)
+}
+
+func TestDecode(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	token := encode(`{"alg":"none"}`) + "." + encode(`{"sub":"user","scope":"openid"}`) + "."
+
+	code, stdout, _ := runCLI("decode", token)
+	require.Equal(t, 0, code)
+	require.Equal(t, "{\n  \"scope\": \"openid\",\n  \"sub\": \"user\"\n}\n", stdout)
+
+	code, _, _ = runCLI("decode", "not-a-token")
+	require.Equal(t, 1, code)
+
+	token = encode(`{"alg":"none"}`) + "." + encode(`{"realm_access":"admin"}`) + "."
+
+	code, _, _ = runCLI("decode", token)
+	require.Equal(t, 1, code)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/real-evolution/recloak/authz"
+	"github.com/real-evolution/recloak/config"
+)
+
+func runTree(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		return usageError(stderr, treeUsage)
+	}
+
+	engine, err := loadEngine(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, resource := range engine.ProtectedResources() {
+		expression := resource.Expression
+		if expression == "" {
+			expression = "-"
+		}
+
+		line := resource.Path + "\t" + expression
+		if resource.Keycloak != nil {
+			line += "\tkeycloak: " + resource.Keycloak.Resource
+			if len(resource.Keycloak.Scopes) > 0 {
+				line += "#" + strings.Join(resource.Keycloak.Scopes, ",")
+			}
+		}
+
+		fmt.Fprintln(w, line)
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// loadEngine loads the configuration file at the given path, and builds its
+// authorization engine.
+func loadEngine(path string) (*authz.Engine, error) {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	engine, err := authz.NewEngine(&cfg.Authz)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return engine, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/real-evolution/recloak/authz"
+	"github.com/real-evolution/recloak/config"
+)
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		return usageError(stderr, validateUsage)
+	}
+
+	path := args[0]
+
+	errs := validate(path)
+	for _, err := range errs {
+		fmt.Fprintln(stderr, err)
+	}
+
+	if len(errs) > 0 {
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%s: ok\n", path)
+
+	return 0
+}
+
+// validate checks the configuration file at the given path, and returns all
+// the errors found, located at their YAML lines where possible.
+func validate(path string) []error {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return []error{fmt.Errorf("%s: %w", path, err)}
+	}
+
+	if _, err := authz.NewEngine(&cfg.Authz); err != nil {
+		var configErrs authz.ConfigErrors
+		if errors.As(err, &configErrs) {
+			errs := make([]error, len(configErrs))
+			for i, configErr := range configErrs {
+				errs[i] = configErr
+			}
+
+			return errs
+		}
+
+		return []error{fmt.Errorf("%s: %w", path, err)}
+	}
+
+	return nil
+}
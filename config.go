@@ -13,6 +13,9 @@ type ClientConfig struct {
 	Realm         string `yaml:"realm"`
 	ClientID      string `yaml:"clientId"`
 	ClientSecret  string `yaml:"clientSecret"`
+
+	// Access token claims validation options.
+	Validation ValidationOptions `yaml:"validation,omitempty"`
 }
 
 // NewClientConfigFromURL creates a new `ClientConfig` from the given URL.
@@ -55,10 +58,11 @@ func (c *ClientConfig) ToURL() (*url.URL, error) {
 
 func (c *ClientConfig) UnmarshalYAML(node *yaml.Node) (err error) {
 	type clientConfigModel struct {
-		AuthServerURL string `yaml:"authServerUrl"`
-		Realm         string `yaml:"realm"`
-		ClientID      string `yaml:"clientId"`
-		ClientSecret  string `yaml:"clientSecret"`
+		AuthServerURL string            `yaml:"authServerUrl"`
+		Realm         string            `yaml:"realm"`
+		ClientID      string            `yaml:"clientId"`
+		ClientSecret  string            `yaml:"clientSecret"`
+		Validation    ValidationOptions `yaml:"validation,omitempty"`
 	}
 
 	if node == nil {
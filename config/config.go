@@ -30,8 +30,20 @@ func LoadConfig(path string) (*ReCloakConfig, error) {
 		return nil, err
 	}
 
+	config, err := ParseConfig(fileContent)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Authz.File = path
+
+	return config, nil
+}
+
+// ParseConfig parses a configuration from the given YAML content.
+func ParseConfig(content []byte) (*ReCloakConfig, error) {
 	var config ReCloakConfig
-	if err := yaml.Unmarshal(fileContent, &config); err != nil {
+	if err := yaml.Unmarshal(content, &config); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak/authz"
+)
+
+// DefaultWatchInterval is the default interval at which configuration files
+// are checked for changes.
+const DefaultWatchInterval = 5 * time.Second
+
+// ConfigWatcher is a type that reloads the authorization configuration of an
+// engine whenever the content of a configuration file changes.
+type ConfigWatcher struct {
+	path     string
+	engine   *authz.ReloadableEngine
+	lastHash []byte
+}
+
+// NewConfigWatcher creates a new watcher of the configuration file at the
+// given path. The current configuration of the engine is assumed to match the
+// content of the file at the time of the call.
+func NewConfigWatcher(
+	path string,
+	engine *authz.ReloadableEngine,
+) (*ConfigWatcher, error) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigWatcher{
+		path:     path,
+		engine:   engine,
+		lastHash: hash,
+	}, nil
+}
+
+// Run checks the configuration file for changes at the given interval until
+// the given context is done.
+//
+// Invalid configurations are reported through the engine reload events, and
+// the engine keeps serving its current configuration.
+func (w *ConfigWatcher) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reloads the configuration if the content of the file changed. The
+// file is read once, so that the reloaded content always matches the stored
+// hash.
+func (w *ConfigWatcher) check() {
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", w.path).Msg("could not read config file")
+		return
+	}
+
+	hash := hashContent(content)
+	if bytes.Equal(hash, w.lastHash) {
+		return
+	}
+	w.lastHash = hash
+
+	_ = w.engine.ReloadFrom(w.path, func() (*authz.AuthzConfig, error) {
+		config, err := ParseConfig(content)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Authz.File = w.path
+
+		return &config.Authz, nil
+	})
+}
+
+func hashFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashContent(content), nil
+}
+
+func hashContent(content []byte) []byte {
+	hash := sha256.Sum256(content)
+
+	return hash[:]
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak/authz"
+)
+
+const watchTestConfigFmt = `
+---
+client:
+  authServerUrl: https://auth.example.com
+  realm: test
+  clientId: client
+authz:
+  pathSeparator: .
+  enforcementMode: enforcing
+  resources:
+    - name: resource
+      policy:
+        expression: %s
+`
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(expression string) {
+		content := []byte(fmt.Sprintf(watchTestConfigFmt, expression))
+		require.NoError(t, os.WriteFile(path, content, 0o600))
+	}
+
+	writeConfig("false")
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	engine, err := authz.NewReloadableEngine(&config.Authz)
+	require.NoError(t, err)
+
+	events := make(chan authz.ReloadEvent, 8)
+	engine.OnReload(func(event authz.ReloadEvent) { events <- event })
+
+	watcher, err := NewConfigWatcher(path, engine)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() { _ = watcher.Run(ctx, 10*time.Millisecond) }()
+
+	writeConfig("true")
+
+	event := <-events
+	require.NoError(t, event.Err)
+	require.Equal(t, path, event.Source)
+	require.NoError(t, engine.Authorize("resource", nil, nil))
+	require.Equal(t, "client", engine.Config().ClientID)
+
+	writeConfig("'unterminated")
+
+	event = <-events
+	require.Error(t, event.Err)
+	require.NoError(t, engine.Authorize("resource", nil, nil))
+}
@@ -0,0 +1,300 @@
+package recloak
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// GrantTypeTokenExchange is the grant type of token exchange requests, as
+	// defined by RFC 8693.
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// TokenTypeAccessToken is the type of access tokens, as defined by RFC 8693.
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	// TokenTypeRefreshToken is the type of refresh tokens, as defined by
+	// RFC 8693.
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+)
+
+// TokenExchangeOptions is a type that holds the parameters of a token
+// exchange request.
+type TokenExchangeOptions struct {
+	// The token to exchange, usually the access token of the caller.
+	SubjectToken string
+
+	// The type of the subject token, which defaults to an access token.
+	SubjectTokenType string
+
+	// The client that the exchanged token is intended for.
+	Audience string
+
+	// The type of the requested token, which defaults to an access token.
+	RequestedTokenType string
+}
+
+// TokenExchange exchanges a token for another one, e.g. scoped to the audience
+// of a downstream service, using the token exchange grant (RFC 8693).
+//
+// Exchanged tokens are cached per subject token, audience and requested token
+// type until they are about to expire, and never beyond the expiry of the
+// subject token. Concurrent exchanges of the same token are coalesced into a
+// single request.
+func (r *ReCloak) TokenExchange(
+	ctx context.Context,
+	options TokenExchangeOptions,
+) (*gocloak.JWT, error) {
+	if options.SubjectTokenType == "" {
+		options.SubjectTokenType = TokenTypeAccessToken
+	}
+
+	if options.RequestedTokenType == "" {
+		options.RequestedTokenType = TokenTypeAccessToken
+	}
+
+	key := options.cacheKey()
+
+	token, call, leader := r.exchanges.acquire(key)
+	if token != nil {
+		return token, nil
+	}
+
+	if !leader {
+		select {
+		case <-call.done:
+			return call.token, call.err
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	issuedAt := time.Now()
+	token, err := r.exchange(ctx, options)
+
+	r.exchanges.complete(key, call, token, err, issuedAt, subjectExpiry(options.SubjectToken))
+
+	return token, err
+}
+
+// exchange requests the token exchange from keycloak.
+func (r *ReCloak) exchange(
+	ctx context.Context,
+	options TokenExchangeOptions,
+) (*gocloak.JWT, error) {
+	tokenURL, err := url.JoinPath(
+		r.config.AuthServerURL,
+		"realms",
+		r.config.Realm,
+		"protocol",
+		"openid-connect",
+		"token",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	form := map[string]string{
+		"grant_type":           GrantTypeTokenExchange,
+		"client_id":            r.config.ClientID,
+		"subject_token":        options.SubjectToken,
+		"subject_token_type":   options.SubjectTokenType,
+		"requested_token_type": options.RequestedTokenType,
+	}
+	if options.Audience != "" {
+		form["audience"] = options.Audience
+	}
+
+	var token gocloak.JWT
+
+	resp, err := r.client.
+		GetRequestWithBasicAuth(ctx, r.config.ClientID, r.config.ClientSecret).
+		SetFormData(form).
+		SetResult(&token).
+		Post(tokenURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, &APIError{
+			Code:    resp.StatusCode(),
+			Message: "could not exchange token: " + resp.Status(),
+			Type:    gocloak.APIErrTypeUnknown,
+		}
+	}
+
+	return &token, nil
+}
+
+// cacheKey returns the key of the exchanged token in the cache. The subject
+// token is hashed to not keep it in memory.
+func (o TokenExchangeOptions) cacheKey() string {
+	hash := sha256.Sum256([]byte(o.SubjectToken))
+
+	return hex.EncodeToString(hash[:]) + "|" +
+		o.SubjectTokenType + "|" +
+		o.Audience + "|" +
+		o.RequestedTokenType
+}
+
+// subjectExpiry returns the expiry of the given subject token, or the zero
+// time if it is not a JWT with an expiry. The token is not verified, as it is
+// only used to bound how long exchanged tokens are cached.
+func subjectExpiry(subjectToken string) time.Time {
+	var claims jwt.RegisteredClaims
+
+	_, _, err := jwt.NewParser().ParseUnverified(subjectToken, &claims)
+	if err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+
+	return claims.ExpiresAt.Time
+}
+
+// DefaultExchangeCacheSize is the maximum number of cached exchanged tokens,
+// beyond which the least recently used ones are evicted.
+const DefaultExchangeCacheSize = 1000
+
+// exchangeCache is a size-bounded LRU cache of exchanged tokens, which also
+// tracks the exchanges in flight. It is safe for concurrent use.
+type exchangeCache struct {
+	expiryDelta time.Duration
+	maxSize     int
+	now         func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+	inflight map[string]*exchangeCall
+}
+
+type exchangeCacheEntry struct {
+	key       string
+	token     *gocloak.JWT
+	expiresAt time.Time
+}
+
+// exchangeCall is an exchange in flight, whose result is available once done
+// is closed.
+type exchangeCall struct {
+	done  chan struct{}
+	token *gocloak.JWT
+	err   error
+}
+
+func newExchangeCache() *exchangeCache {
+	return &exchangeCache{
+		expiryDelta: DefaultTokenExpiryDelta,
+		maxSize:     DefaultExchangeCacheSize,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		inflight:    make(map[string]*exchangeCall),
+	}
+}
+
+// acquire returns the cached token of the key if it is valid. Otherwise, it
+// returns the exchange in flight for the key, starting one if there is none,
+// in which case the caller is the leader and must complete it.
+func (c *exchangeCache) acquire(key string) (*gocloak.JWT, *exchangeCall, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if token, ok := c.getLocked(key); ok {
+		return token, nil, false
+	}
+
+	if call, ok := c.inflight[key]; ok {
+		return nil, call, false
+	}
+
+	call := &exchangeCall{done: make(chan struct{})}
+	c.inflight[key] = call
+
+	return nil, call, true
+}
+
+// complete completes the exchange in flight for the key with its result,
+// caching the token if it was obtained.
+func (c *exchangeCache) complete(
+	key string,
+	call *exchangeCall,
+	token *gocloak.JWT,
+	err error,
+	issuedAt time.Time,
+	subjectExpiresAt time.Time,
+) {
+	if err == nil {
+		c.put(key, token, issuedAt, subjectExpiresAt)
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+
+	call.token, call.err = token, err
+	close(call.done)
+}
+
+func (c *exchangeCache) getLocked(key string) (*gocloak.JWT, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*exchangeCacheEntry)
+	if !c.now().Add(c.expiryDelta).Before(entry.expiresAt) {
+		c.removeLocked(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return entry.token, true
+}
+
+// put caches the given token until it expires, or until the subject token
+// expires if it is earlier and not zero.
+func (c *exchangeCache) put(
+	key string,
+	token *gocloak.JWT,
+	issuedAt time.Time,
+	subjectExpiresAt time.Time,
+) {
+	expiresAt := issuedAt.Add(time.Duration(token.ExpiresIn) * time.Second)
+	if !subjectExpiresAt.IsZero() && subjectExpiresAt.Before(expiresAt) {
+		expiresAt = subjectExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+
+	for c.lru.Len() >= c.maxSize {
+		c.removeLocked(c.lru.Back())
+	}
+
+	c.entries[key] = c.lru.PushFront(&exchangeCacheEntry{
+		key:       key,
+		token:     token,
+		expiresAt: expiresAt,
+	})
+}
+
+func (c *exchangeCache) removeLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*exchangeCacheEntry)
+	delete(c.entries, entry.key)
+}
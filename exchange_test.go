@@ -0,0 +1,150 @@
+package recloak
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenExchangeCachesTokens(t *testing.T) {
+	server := newTestRealmServer(t)
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	exchange := func(subject, audience string) string {
+		token, err := client.TokenExchange(context.Background(), TokenExchangeOptions{
+			SubjectToken: subject,
+			Audience:     audience,
+		})
+		require.NoError(t, err)
+
+		return token.AccessToken
+	}
+
+	require.Equal(t, "alice-for-orders-1", exchange("alice", "orders"))
+	require.Equal(t, "alice-for-orders-1", exchange("alice", "orders"))
+	require.Equal(t, "alice-for-billing-2", exchange("alice", "billing"))
+	require.Equal(t, "bob-for-orders-3", exchange("bob", "orders"))
+	require.EqualValues(t, 3, server.exchanges.Load())
+}
+
+func TestTokenExchangeError(t *testing.T) {
+	server := newTestRealmServer(t)
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	_, err = client.TokenExchange(context.Background(), TokenExchangeOptions{})
+	require.Error(t, err)
+}
+
+// lookup returns the token cached for the key, if any, through `acquire`,
+// abandoning the exchange that it starts on a miss.
+func lookup(cache *exchangeCache, key string) (*gocloak.JWT, bool) {
+	token, call, leader := cache.acquire(key)
+	if leader {
+		cache.complete(key, call, nil, errors.New("abandoned"), time.Time{}, time.Time{})
+	}
+
+	return token, token != nil
+}
+
+func TestExchangeCacheExpiry(t *testing.T) {
+	cache := newExchangeCache()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.put("key", &gocloak.JWT{AccessToken: "token", ExpiresIn: 60}, now, time.Time{})
+
+	token, ok := lookup(cache, "key")
+	require.True(t, ok)
+	require.Equal(t, "token", token.AccessToken)
+
+	now = now.Add(60*time.Second - DefaultTokenExpiryDelta)
+
+	_, ok = lookup(cache, "key")
+	require.False(t, ok)
+	require.Empty(t, cache.entries)
+}
+
+func TestExchangeCacheSubjectExpiry(t *testing.T) {
+	cache := newExchangeCache()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.put("key", &gocloak.JWT{ExpiresIn: 300}, now, now.Add(time.Minute))
+
+	_, ok := lookup(cache, "key")
+	require.True(t, ok)
+
+	now = now.Add(time.Minute - DefaultTokenExpiryDelta)
+
+	_, ok = lookup(cache, "key")
+	require.False(t, ok)
+}
+
+func TestExchangeCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newExchangeCache()
+	cache.maxSize = 2
+
+	now := time.Now()
+	cache.put("a", &gocloak.JWT{ExpiresIn: 60}, now, time.Time{})
+	cache.put("b", &gocloak.JWT{ExpiresIn: 60}, now, time.Time{})
+
+	_, ok := lookup(cache, "a")
+	require.True(t, ok)
+
+	cache.put("c", &gocloak.JWT{ExpiresIn: 60}, now, time.Time{})
+	require.Len(t, cache.entries, 2)
+
+	_, ok = lookup(cache, "b")
+	require.False(t, ok)
+
+	_, ok = lookup(cache, "a")
+	require.True(t, ok)
+}
+
+func TestTokenExchangeCoalescesRequests(t *testing.T) {
+	server := newTestRealmServer(t)
+	server.tokenDelay = 50 * time.Millisecond
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := client.TokenExchange(context.Background(), TokenExchangeOptions{
+				SubjectToken: "alice",
+				Audience:     "orders",
+			})
+			require.NoError(t, err)
+			require.Equal(t, "alice-for-orders-1", token.AccessToken)
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, 1, server.exchanges.Load())
+}
+
+func TestSubjectExpiry(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	require.True(t, expiresAt.Equal(subjectExpiry(token)))
+	require.True(t, subjectExpiry("opaque").IsZero())
+}
@@ -0,0 +1,123 @@
+// Package testrealm provides a fake keycloak realm for tests, which publishes
+// its signing key and signs access tokens for its client.
+package testrealm
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak"
+)
+
+const (
+	// Realm is the name of the realm.
+	Realm = "test"
+
+	// ClientID is the ID of the client that tokens are signed for.
+	ClientID = "client"
+
+	keyID = "test"
+)
+
+// Server is a fake keycloak realm. Tests may register more endpoints on its
+// mux.
+type Server struct {
+	*httptest.Server
+
+	Mux *http.ServeMux
+
+	key *rsa.PrivateKey
+}
+
+// NewServer starts a new fake realm, which is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	s := &Server{
+		Mux: http.NewServeMux(),
+		key: key,
+	}
+
+	s.Mux.HandleFunc(
+		"/realms/"+Realm+"/protocol/openid-connect/certs",
+		s.serveCerts,
+	)
+
+	s.Server = httptest.NewServer(s.Mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Config returns the configuration of the client of the realm.
+func (s *Server) Config() *recloak.ClientConfig {
+	return &recloak.ClientConfig{
+		AuthServerURL: s.URL,
+		Realm:         Realm,
+		ClientID:      ClientID,
+		ClientSecret:  "secret",
+	}
+}
+
+// Client returns a new client of the realm.
+func (s *Server) Client(t testing.TB) *recloak.ReCloak {
+	t.Helper()
+
+	client, err := recloak.NewClient(s.Config())
+	require.NoError(t, err)
+
+	return client
+}
+
+// Token returns a valid access token of the client for the `user` subject,
+// with the given extra claims.
+func (s *Server) Token(t testing.TB, extra jwt.MapClaims) string {
+	t.Helper()
+
+	claims := jwt.MapClaims{
+		"iss": s.URL + "/realms/" + Realm,
+		"sub": "user",
+		"aud": ClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	maps.Copy(claims, extra)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+
+	signed, err := token.SignedString(s.key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func (s *Server) serveCerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(
+				big.NewInt(int64(s.key.E)).Bytes(),
+			),
+		}},
+	})
+}
@@ -0,0 +1,320 @@
+package recloak
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultKeysRefreshInterval is the age after which cached realm keys are
+	// refreshed in the background.
+	DefaultKeysRefreshInterval = 10 * time.Minute
+
+	// DefaultKeysMinRefetchInterval is the minimum time between two fetches
+	// triggered by tokens signed with an unknown key.
+	DefaultKeysMinRefetchInterval = 10 * time.Second
+
+	// keysFetchTimeout bounds background key refreshes.
+	keysFetchTimeout = 10 * time.Second
+)
+
+// ErrUnknownSigningKey is returned when a token is signed with a key that is
+// not published by the realm.
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// ErrKeysUnavailable is returned when the realm signing keys could not be
+// fetched.
+var ErrKeysUnavailable = errors.New("signing keys are unavailable")
+
+// signingMethods are the JWT signing methods accepted for access tokens.
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+}
+
+// KeySet is a cache of the realm signing keys (JWKS), indexed by key ID, that
+// is used to verify access tokens locally.
+//
+// Keys are fetched lazily on first use, refreshed in the background once they
+// get older than the refresh interval, and refetched synchronously when a
+// token references an unknown key ID (e.g. after a key rotation).
+type KeySet struct {
+	client             *gocloak.GoCloak
+	certsURL           string
+	refreshInterval    time.Duration
+	minRefetchInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+
+	fetchMu    sync.Mutex
+	refreshing atomic.Bool
+}
+
+// NewKeySet creates a new key set for the realm of the given configuration.
+func NewKeySet(client *gocloak.GoCloak, config *ClientConfig) (*KeySet, error) {
+	certsURL, err := url.JoinPath(
+		config.AuthServerURL,
+		"realms",
+		config.Realm,
+		"protocol",
+		"openid-connect",
+		"certs",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySet{
+		client:             client,
+		certsURL:           certsURL,
+		refreshInterval:    DefaultKeysRefreshInterval,
+		minRefetchInterval: DefaultKeysMinRefetchInterval,
+		keys:               make(map[string]crypto.PublicKey),
+	}, nil
+}
+
+// SetRefreshInterval sets the age after which keys are refreshed in the
+// background.
+func (s *KeySet) SetRefreshInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshInterval = interval
+}
+
+// Parse parses and verifies the given token string, decoding its claims into
+// the given claims value.
+func (s *KeySet) Parse(
+	ctx context.Context,
+	tokenString string,
+	claims jwt.Claims,
+	opts ...jwt.ParserOption,
+) (*jwt.Token, error) {
+	opts = append(opts, jwt.WithValidMethods(signingMethods))
+	parser := jwt.NewParser(opts...)
+
+	return parser.ParseWithClaims(
+		tokenString,
+		claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+
+			return s.Lookup(ctx, kid)
+		},
+	)
+}
+
+// Lookup returns the public key with the given ID, fetching the realm keys if
+// the key is not known yet.
+func (s *KeySet) Lookup(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok, age := s.get(kid)
+
+	switch {
+	case ok && age > s.getRefreshInterval():
+		s.refreshInBackground()
+
+	case !ok && (age < 0 || age > s.minRefetchInterval):
+		if err := s.Refresh(ctx); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+		}
+
+		key, ok, _ = s.get(kid)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSigningKey, kid)
+	}
+
+	return key, nil
+}
+
+// Refresh fetches the realm keys, replacing the cached ones. Concurrent calls
+// are coalesced into a single fetch.
+func (s *KeySet) Refresh(ctx context.Context) error {
+	requestedAt := time.Now()
+
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.mu.RLock()
+	fetchedAt := s.fetchedAt
+	s.mu.RUnlock()
+
+	// another caller fetched the keys while we were waiting
+	if fetchedAt.After(requestedAt) {
+		return nil
+	}
+
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *KeySet) refreshInBackground() {
+	if !s.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer s.refreshing.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.Background(), keysFetchTimeout)
+		defer cancel()
+
+		if err := s.Refresh(ctx); err != nil {
+			log.Warn().Err(err).Msg("could not refresh realm keys")
+		}
+	}()
+}
+
+// get returns the cached key with the given ID and the age of the cache, which
+// is negative if the keys were never fetched.
+func (s *KeySet) get(kid string) (crypto.PublicKey, bool, time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	age := time.Duration(-1)
+	if !s.fetchedAt.IsZero() {
+		age = time.Since(s.fetchedAt)
+	}
+
+	key, ok := s.keys[kid]
+
+	return key, ok, age
+}
+
+func (s *KeySet) getRefreshInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.refreshInterval
+}
+
+func (s *KeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var result gocloak.CertResponse
+
+	resp, err := s.client.GetRequest(ctx).
+		SetResult(&result).
+		Get(s.certsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, &APIError{
+			Code:    resp.StatusCode(),
+			Message: "could not get realm keys: " + resp.Status(),
+			Type:    gocloak.APIErrTypeUnknown,
+		}
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	if result.Keys == nil {
+		return keys, nil
+	}
+
+	for _, jwk := range *result.Keys {
+		if jwk.Kid == nil || (jwk.Use != nil && *jwk.Use != "sig") {
+			continue
+		}
+
+		key, err := decodePublicKey(jwk)
+		if err != nil {
+			log.Warn().Err(err).Str("kid", *jwk.Kid).Msg("skipping realm key")
+			continue
+		}
+
+		keys[*jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func decodePublicKey(jwk gocloak.CertResponseKey) (crypto.PublicKey, error) {
+	if jwk.Kty == nil {
+		return nil, errors.New("missing key type")
+	}
+
+	switch *jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+
+		switch gocloak.PString(jwk.Crv) {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", gocloak.PString(jwk.Crv))
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", *jwk.Kty)
+	}
+}
+
+func decodeBigInt(value *string) (*big.Int, error) {
+	if value == nil {
+		return nil, errors.New("missing key parameter")
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(*value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
@@ -0,0 +1,101 @@
+package recloak
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeAccessTokenLocally(t *testing.T) {
+	server := newTestRealmServer(t)
+	key := server.rotate(t, "first")
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	raw := signTestToken(t, key, "first", server.claims())
+
+	for range 3 {
+		token, err := client.DecodeAccessToken(context.Background(), raw)
+		require.NoError(t, err)
+		require.True(t, token.Valid)
+		require.Equal(t, "user", token.Claims.Subject)
+		require.Equal(t, "username", token.Claims.PreferredUsername)
+	}
+
+	require.EqualValues(t, 1, server.fetches.Load())
+}
+
+func TestDecodeAccessTokenKeyRotation(t *testing.T) {
+	server := newTestRealmServer(t)
+	oldKey := server.rotate(t, "old")
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+	client.Keys().minRefetchInterval = 0
+
+	_, err = client.DecodeAccessToken(
+		context.Background(),
+		signTestToken(t, oldKey, "old", server.claims()),
+	)
+	require.NoError(t, err)
+
+	newKey := server.rotate(t, "new")
+
+	_, err = client.DecodeAccessToken(
+		context.Background(),
+		signTestToken(t, newKey, "new", server.claims()),
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, server.fetches.Load())
+
+	_, err = client.DecodeAccessToken(
+		context.Background(),
+		signTestToken(t, oldKey, "old", server.claims()),
+	)
+	require.ErrorIs(t, err, ErrUnknownSigningKey)
+}
+
+func TestDecodeAccessTokenRejectsForgedToken(t *testing.T) {
+	server := newTestRealmServer(t)
+	server.rotate(t, "first")
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	_, err = client.DecodeAccessToken(
+		context.Background(),
+		signTestToken(t, forger, "first", server.claims()),
+	)
+	require.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestKeySetBackgroundRefresh(t *testing.T) {
+	server := newTestRealmServer(t)
+	key := server.rotate(t, "first")
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	raw := signTestToken(t, key, "first", server.claims())
+
+	_, err = client.DecodeAccessToken(context.Background(), raw)
+	require.NoError(t, err)
+
+	client.Keys().SetRefreshInterval(0)
+
+	_, err = client.DecodeAccessToken(context.Background(), raw)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return server.fetches.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+}
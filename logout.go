@@ -0,0 +1,137 @@
+package recloak
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// BackChannelLogoutEvent is the event that a logout token carries, as defined
+// by the OIDC back-channel logout specification.
+const BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// DefaultLogoutTokenLifetime is how long a logout token without an expiry is
+// accepted after it was issued.
+const DefaultLogoutTokenLifetime = 2 * time.Minute
+
+// ErrInvalidLogoutToken is returned when a logout token is not valid according
+// to the OIDC back-channel logout specification.
+var ErrInvalidLogoutToken = errors.New("invalid logout token")
+
+// LogoutClaims is a type that represents the claims of a back-channel logout
+// token.
+type LogoutClaims struct {
+	jwt.RegisteredClaims
+
+	// The ID of the logged out session.
+	SessionID string `json:"sid,omitempty"`
+
+	// The events of the token, which must contain the back-channel logout
+	// event.
+	Events map[string]any `json:"events,omitempty"`
+
+	// Must not be present in logout tokens.
+	Nonce *string `json:"nonce,omitempty"`
+}
+
+// DecodeLogoutToken decodes and validates a back-channel logout token sent by
+// keycloak when a session is logged out.
+//
+// The token signature and issuer are verified the same way as access tokens,
+// and it must be intended for the client. Its `jti` is kept until it expires,
+// and tokens with an already seen `jti` are rejected as replays.
+func (c *ReCloak) DecodeLogoutToken(
+	ctx context.Context,
+	tokenString string,
+) (*LogoutClaims, error) {
+	opts, err := c.config.Validation.parserOptions(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, jwt.WithAudience(c.config.ClientID))
+
+	claims := &LogoutClaims{}
+	if _, err := c.keys.Parse(ctx, tokenString, claims, opts...); err != nil {
+		return nil, errors.Join(ErrInvalidLogoutToken, err)
+	}
+
+	if _, ok := claims.Events[BackChannelLogoutEvent]; !ok {
+		return nil, errors.Join(ErrInvalidLogoutToken, errors.New("missing logout event"))
+	}
+
+	if claims.SessionID == "" && claims.Subject == "" {
+		return nil, errors.Join(ErrInvalidLogoutToken, errors.New("missing `sid` and `sub` claims"))
+	}
+
+	if claims.Nonce != nil {
+		return nil, errors.Join(ErrInvalidLogoutToken, errors.New("unexpected `nonce` claim"))
+	}
+
+	if claims.ID == "" {
+		return nil, errors.Join(ErrInvalidLogoutToken, errors.New("missing `jti` claim"))
+	}
+
+	var expiresAt time.Time
+	switch {
+	case claims.ExpiresAt != nil:
+		expiresAt = claims.ExpiresAt.Time
+
+	case claims.IssuedAt != nil:
+		expiresAt = claims.IssuedAt.Add(DefaultLogoutTokenLifetime)
+		if time.Now().After(expiresAt.Add(c.config.Validation.Leeway)) {
+			return nil, errors.Join(ErrInvalidLogoutToken, errors.New("logout token is too old"))
+		}
+
+	default:
+		return nil, errors.Join(ErrInvalidLogoutToken, errors.New("missing `exp` and `iat` claims"))
+	}
+
+	// tokens are accepted until they expire, within the leeway
+	if !c.logoutTokens.add(claims.ID, expiresAt.Add(c.config.Validation.Leeway)) {
+		return nil, errors.Join(ErrInvalidLogoutToken, errors.New("replayed logout token"))
+	}
+
+	return claims, nil
+}
+
+// seenTokenIDs is a set of the IDs of tokens that were seen, kept until the
+// tokens expire. It is safe for concurrent use.
+type seenTokenIDs struct {
+	mu  sync.Mutex
+	ids map[string]time.Time
+
+	now func() time.Time
+}
+
+func newSeenTokenIDs() *seenTokenIDs {
+	return &seenTokenIDs{
+		ids: make(map[string]time.Time),
+		now: time.Now,
+	}
+}
+
+// add records the given token ID until the given expiry, returning false if
+// it was already seen.
+func (s *seenTokenIDs) add(id string, expiresAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for seen, seenExpiresAt := range s.ids {
+		if !now.Before(seenExpiresAt) {
+			delete(s.ids, seen)
+		}
+	}
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+
+	s.ids[id] = expiresAt
+
+	return true
+}
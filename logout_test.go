@@ -0,0 +1,101 @@
+package recloak
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLogoutToken(t *testing.T) {
+	server := newTestRealmServer(t)
+	key := server.rotate(t, "first")
+
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	newClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    server.URL + "/realms/" + testRealm,
+			"aud":    "client",
+			"sub":    "user",
+			"sid":    "session",
+			"iat":    time.Now().Unix(),
+			"jti":    "logout-1",
+			"events": map[string]any{BackChannelLogoutEvent: map[string]any{}},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		raw := signTestToken(t, key, "first", newClaims())
+
+		claims, err := client.DecodeLogoutToken(context.Background(), raw)
+		require.NoError(t, err)
+		require.Equal(t, "session", claims.SessionID)
+		require.Equal(t, "user", claims.Subject)
+
+		_, err = client.DecodeLogoutToken(context.Background(), raw)
+		require.ErrorIs(t, err, ErrInvalidLogoutToken)
+		require.ErrorContains(t, err, "replayed logout token")
+	})
+
+	t.Run("valid with expiry", func(t *testing.T) {
+		claims := newClaims()
+		claims["jti"] = "logout-2"
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+
+		raw := signTestToken(t, key, "first", claims)
+
+		_, err := client.DecodeLogoutToken(context.Background(), raw)
+		require.NoError(t, err)
+
+		_, err = client.DecodeLogoutToken(context.Background(), raw)
+		require.ErrorIs(t, err, ErrInvalidLogoutToken)
+	})
+
+	invalid := map[string]func(jwt.MapClaims){
+		"missing id": func(c jwt.MapClaims) { c["jti"] = "" },
+		"too old": func(c jwt.MapClaims) {
+			c["jti"] = "logout-3"
+			c["iat"] = time.Now().Add(-DefaultLogoutTokenLifetime - time.Minute).Unix()
+		},
+		"missing issue time": func(c jwt.MapClaims) { c["jti"] = "logout-4"; delete(c, "iat") },
+		"wrong audience":     func(c jwt.MapClaims) { c["aud"] = "other" },
+		"wrong issuer":       func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"missing event":      func(c jwt.MapClaims) { c["events"] = map[string]any{} },
+		"missing subject":    func(c jwt.MapClaims) { delete(c, "sid"); delete(c, "sub") },
+		"nonce":              func(c jwt.MapClaims) { c["nonce"] = "nonce" },
+	}
+
+	for name, modify := range invalid {
+		t.Run(name, func(t *testing.T) {
+			claims := newClaims()
+			modify(claims)
+
+			raw := signTestToken(t, key, "first", claims)
+
+			_, err := client.DecodeLogoutToken(context.Background(), raw)
+			require.ErrorIs(t, err, ErrInvalidLogoutToken)
+		})
+	}
+}
+
+func TestSeenTokenIDs(t *testing.T) {
+	seen := newSeenTokenIDs()
+
+	now := time.Now()
+	seen.now = func() time.Time { return now }
+
+	require.True(t, seen.add("a", now.Add(time.Minute)))
+	require.True(t, seen.add("b", now.Add(2*time.Minute)))
+	require.False(t, seen.add("a", now.Add(time.Minute)))
+
+	// IDs are forgotten once their tokens expire
+	now = now.Add(time.Minute)
+
+	require.False(t, seen.add("b", now.Add(time.Minute)))
+	require.Len(t, seen.ids, 1)
+	require.True(t, seen.add("a", now.Add(time.Minute)))
+}
@@ -0,0 +1,253 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/real-evolution/recloak"
+)
+
+// PropagationMode is an enum that indicates which token is attached to
+// outgoing calls.
+type PropagationMode int
+
+const (
+	// PropagationModeNone is the propagation mode that causes the service
+	// account token to be attached to outgoing calls.
+	PropagationModeNone PropagationMode = iota
+
+	// PropagationModeForward is the propagation mode that causes the token of
+	// the incoming call, found in the context, to be attached to outgoing
+	// calls. The service account token is attached if there is none.
+	PropagationModeForward
+
+	// PropagationModeExchange is the propagation mode that causes the token of
+	// the incoming call, found in the context, to be exchanged for one
+	// intended for the called service, which is attached to outgoing calls.
+	// The service account token is attached if there is none.
+	PropagationModeExchange
+)
+
+// TokenExchanger is a type that exchanges tokens, e.g. `recloak.ReCloak`.
+type TokenExchanger interface {
+	// TokenExchange exchanges a token for another one.
+	TokenExchange(
+		ctx context.Context,
+		options recloak.TokenExchangeOptions,
+	) (*gocloak.JWT, error)
+}
+
+// Ensure TokenCredentials implements credentials.PerRPCCredentials.
+var _ credentials.PerRPCCredentials = TokenCredentials{}
+
+// TokenCredentials is a `credentials.PerRPCCredentials` that attaches bearer
+// tokens to outgoing calls.
+type TokenCredentials struct {
+	tokens      recloak.TokenSource
+	propagation PropagationMode
+	insecure    bool
+
+	exchanger TokenExchanger
+	audience  string
+}
+
+// NewTokenCredentials creates new per-RPC credentials that attach tokens of
+// the given source, which are refreshed before they expire.
+func NewTokenCredentials(tokens recloak.TokenSource) TokenCredentials {
+	return TokenCredentials{tokens: tokens}
+}
+
+// WithPropagation returns a copy of the credentials that uses the given
+// propagation mode. `PropagationModeExchange` needs an exchanger, set with
+// `WithTokenExchange`, without which calls carrying an incoming token fail.
+func (c TokenCredentials) WithPropagation(mode PropagationMode) TokenCredentials {
+	c.propagation = mode
+
+	return c
+}
+
+// WithTokenExchange returns a copy of the credentials that exchanges the token
+// of the incoming call for one intended for the given audience.
+func (c TokenCredentials) WithTokenExchange(
+	exchanger TokenExchanger,
+	audience string,
+) TokenCredentials {
+	c.propagation = PropagationModeExchange
+	c.exchanger = exchanger
+	c.audience = audience
+
+	return c
+}
+
+// WithInsecureTransport returns a copy of the credentials that may be sent
+// over insecure connections.
+func (c TokenCredentials) WithInsecureTransport() TokenCredentials {
+	c.insecure = true
+
+	return c
+}
+
+// GetRequestMetadata implements `credentials.PerRPCCredentials`.
+func (c TokenCredentials) GetRequestMetadata(
+	ctx context.Context,
+	_ ...string,
+) (map[string]string, error) {
+	accessToken, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"authorization": "Bearer " + accessToken}, nil
+}
+
+// RequireTransportSecurity implements `credentials.PerRPCCredentials`.
+func (c TokenCredentials) RequireTransportSecurity() bool {
+	return !c.insecure
+}
+
+func (c TokenCredentials) accessToken(ctx context.Context) (string, error) {
+	if incoming, err := recloak.TokenFromContext(ctx); err == nil {
+		switch c.propagation {
+		case PropagationModeForward:
+			return incoming.Raw, nil
+
+		case PropagationModeExchange:
+			return c.exchangeToken(ctx, incoming.Raw)
+		}
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("could not get service account token")
+
+		return "", status.Error(codes.Unauthenticated, "could not get access token")
+	}
+
+	return token.AccessToken, nil
+}
+
+func (c TokenCredentials) exchangeToken(
+	ctx context.Context,
+	subjectToken string,
+) (string, error) {
+	if c.exchanger == nil {
+		log.Error().Msg("token exchange propagation is used without an exchanger")
+
+		return "", status.Error(codes.Internal, "token exchange is not configured")
+	}
+
+	token, err := c.exchanger.TokenExchange(ctx, recloak.TokenExchangeOptions{
+		SubjectToken: subjectToken,
+		Audience:     c.audience,
+	})
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("audience", c.audience).
+			Msg("could not exchange token")
+
+		return "", status.Error(codes.Unauthenticated, "could not exchange access token")
+	}
+
+	return token.AccessToken, nil
+}
+
+// ClientInterceptor is a gRPC client interceptor that attaches bearer tokens
+// to outgoing calls.
+type ClientInterceptor struct {
+	credentials TokenCredentials
+}
+
+// NewGrpcClientInterceptor creates a new gRPC client interceptor that attaches
+// tokens of the given source, which are refreshed before they expire.
+func NewGrpcClientInterceptor(tokens recloak.TokenSource) ClientInterceptor {
+	return ClientInterceptor{credentials: NewTokenCredentials(tokens)}
+}
+
+// WithPropagation returns a copy of the interceptor that uses the given
+// propagation mode. `PropagationModeExchange` needs an exchanger, set with
+// `WithTokenExchange`, without which calls carrying an incoming token fail.
+func (i ClientInterceptor) WithPropagation(mode PropagationMode) ClientInterceptor {
+	i.credentials = i.credentials.WithPropagation(mode)
+
+	return i
+}
+
+// WithTokenExchange returns a copy of the interceptor that exchanges the token
+// of the incoming call for one intended for the given audience.
+func (i ClientInterceptor) WithTokenExchange(
+	exchanger TokenExchanger,
+	audience string,
+) ClientInterceptor {
+	i.credentials = i.credentials.WithTokenExchange(exchanger, audience)
+
+	return i
+}
+
+// Unary returns a new unary client interceptor that attaches a token to unary
+// RPC calls.
+func (i *ClientInterceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, err := i.attachToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// Stream returns a new streaming client interceptor that attaches a token to
+// streaming RPC calls.
+func (i *ClientInterceptor) Stream() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, err := i.attachToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// attachToken attaches a token to the outgoing metadata of the context, unless
+// it already has an authorization header.
+func (i *ClientInterceptor) attachToken(ctx context.Context) (context.Context, error) {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if len(md.Get("authorization")) > 0 {
+			return ctx, nil
+		}
+	}
+
+	accessToken, err := i.credentials.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.AppendToOutgoingContext(
+		ctx,
+		"authorization",
+		"Bearer "+accessToken,
+	), nil
+}
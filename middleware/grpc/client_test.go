@@ -0,0 +1,165 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/real-evolution/recloak"
+)
+
+type staticTokenSource struct {
+	accessToken string
+	err         error
+}
+
+func (s staticTokenSource) Token(context.Context) (*gocloak.JWT, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &gocloak.JWT{AccessToken: s.accessToken}, nil
+}
+
+type testTokenExchanger struct{}
+
+func (testTokenExchanger) TokenExchange(
+	_ context.Context,
+	options recloak.TokenExchangeOptions,
+) (*gocloak.JWT, error) {
+	if options.Audience == "" {
+		return nil, errors.New("missing audience")
+	}
+
+	return &gocloak.JWT{
+		AccessToken: options.SubjectToken + "-for-" + options.Audience,
+	}, nil
+}
+
+func TestClientInterceptor(t *testing.T) {
+	invoke := func(
+		interceptor ClientInterceptor,
+		ctx context.Context,
+	) ([]string, error) {
+		var authorization []string
+
+		invoker := func(
+			ctx context.Context,
+			_ string,
+			_, _ any,
+			_ *grpc.ClientConn,
+			_ ...grpc.CallOption,
+		) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			authorization = md.Get("authorization")
+
+			return nil
+		}
+
+		err := interceptor.Unary()(ctx, "/pkg.Service/Method", nil, nil, nil, invoker)
+
+		return authorization, err
+	}
+
+	interceptor := NewGrpcClientInterceptor(staticTokenSource{accessToken: "service"})
+
+	incoming := recloak.Token{
+		Token:  &jwt.Token{Raw: "caller", Valid: true},
+		Claims: &recloak.Claims{},
+	}
+	callerCtx := incoming.WrapContext(context.Background())
+
+	t.Run("service account", func(t *testing.T) {
+		authorization, err := invoke(interceptor, callerCtx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer service"}, authorization)
+	})
+
+	t.Run("forward", func(t *testing.T) {
+		forwarding := interceptor.WithPropagation(PropagationModeForward)
+
+		authorization, err := invoke(forwarding, callerCtx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer caller"}, authorization)
+
+		authorization, err = invoke(forwarding, context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer service"}, authorization)
+	})
+
+	t.Run("exchange", func(t *testing.T) {
+		exchanging := interceptor.WithTokenExchange(testTokenExchanger{}, "orders")
+
+		authorization, err := invoke(exchanging, callerCtx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer caller-for-orders"}, authorization)
+
+		authorization, err = invoke(exchanging, context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer service"}, authorization)
+
+		failing := interceptor.WithTokenExchange(testTokenExchanger{}, "")
+
+		_, err = invoke(failing, callerCtx)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		// the exchange propagation mode needs an exchanger
+		unconfigured := interceptor.WithPropagation(PropagationModeExchange)
+
+		_, err = invoke(unconfigured, callerCtx)
+		require.Equal(t, codes.Internal, status.Code(err))
+
+		authorization, err = invoke(unconfigured, context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer service"}, authorization)
+	})
+
+	t.Run("explicit header", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(
+			context.Background(),
+			"authorization",
+			"Bearer explicit",
+		)
+
+		authorization, err := invoke(interceptor, ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer explicit"}, authorization)
+	})
+
+	t.Run("token error", func(t *testing.T) {
+		failing := NewGrpcClientInterceptor(
+			staticTokenSource{err: errors.New("keycloak is down")},
+		)
+
+		_, err := invoke(failing, context.Background())
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestTokenCredentials(t *testing.T) {
+	creds := NewTokenCredentials(staticTokenSource{accessToken: "service"})
+	require.True(t, creds.RequireTransportSecurity())
+	require.False(t, creds.WithInsecureTransport().RequireTransportSecurity())
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"authorization": "Bearer service"}, md)
+
+	incoming := recloak.Token{
+		Token:  &jwt.Token{Raw: "caller", Valid: true},
+		Claims: &recloak.Claims{},
+	}
+
+	_, err = creds.
+		WithPropagation(PropagationModeExchange).
+		GetRequestMetadata(incoming.WrapContext(context.Background()))
+	require.Equal(t, codes.Internal, status.Code(err))
+}
@@ -2,12 +2,14 @@ package grpc
 
 import (
 	"context"
+	"errors"
 
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/real-evolution/recloak"
 	"github.com/real-evolution/recloak/authz"
 )
 
@@ -39,7 +41,10 @@ func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
 }
 
 // Stream returns a new streaming server interceptor that performs authorization
-// on streaming RPC calls.
+// on streaming RPC calls. The opening of streams whose messages are not
+// authorized is authorized with a nil request. Otherwise, the caller is only
+// authenticated when the stream opens, and its messages are then authorized in
+// the configured directions.
 func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
 	return func(
 		srv interface{},
@@ -47,13 +52,78 @@ func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		ctx, err := i.authorize(stream.Context(), info.FullMethod, nil)
+		direction := i.enforcer.Engine().Engine().StreamDirectionOf(info.FullMethod)
+		if direction == authz.StreamDirectionNone {
+			ctx, err := i.authorize(stream.Context(), info.FullMethod, nil)
+			if err != nil {
+				return err
+			}
+
+			return handler(srv, &WrappedServerStream{stream, ctx})
+		}
+
+		ctx, accessToken, token, err := i.authenticate(stream.Context(), info.FullMethod)
 		if err != nil {
 			return err
 		}
 
-		return handler(srv, &WrappedServerStream{stream, ctx})
+		return handler(srv, &AuthorizedServerStream{
+			WrappedServerStream: &WrappedServerStream{stream, ctx},
+			enforcer:            i.enforcer,
+			accessToken:         accessToken,
+			token:               token,
+			fullMethod:          info.FullMethod,
+			direction:           direction,
+		})
+	}
+}
+
+// authenticate authenticates the caller of a streaming call whose messages are
+// authorized, without evaluating the policy of the method, which is evaluated
+// against every message instead.
+func (i *Interceptor) authenticate(
+	ctx context.Context,
+	fullMethod string,
+) (context.Context, string, recloak.Token, error) {
+	log.Debug().Str("fullMethod", fullMethod).Msg("authenticating stream")
+
+	ctx = withRequestID(ctx)
+
+	rawToken, err := extractRawToken(ctx)
+	if err != nil {
+		i.enforcer.DenyUnauthenticated(
+			ctx,
+			fullMethod,
+			errors.New(status.Convert(err).Message()),
+		)
+
+		return nil, "", recloak.Token{}, err
+	}
+
+	engine := i.enforcer.Engine().Engine()
+	if engine.EnforcementModeOf(fullMethod) == authz.EnforcementModeDisabled {
+		return recloak.Token{}.WrapContext(ctx), rawToken, recloak.Token{}, nil
+	}
+
+	token, err := i.enforcer.Authenticate(ctx, rawToken)
+	if err != nil {
+		log.Warn().Err(err).Str("fullMethod", fullMethod).Msg("authentication failed")
+		i.enforcer.DenyUnauthenticated(ctx, fullMethod, err)
+
+		if errors.Is(err, authz.ErrUnavailable) {
+			return nil, "", recloak.Token{}, status.Error(
+				codes.Unavailable,
+				"authorization is unavailable",
+			)
+		}
+
+		return nil, "", recloak.Token{}, status.Error(
+			codes.Unauthenticated,
+			"invalid access token",
+		)
 	}
+
+	return token.WrapContext(ctx), rawToken, token, nil
 }
 
 func (i *Interceptor) authorize(
@@ -77,27 +147,25 @@ func (i *Interceptor) doAuthorize(
 ) (context.Context, error) {
 	log.Debug().Str("fullMethod", fullMethod).Msg("authorizing request")
 
-	header, err := extractAuthorizationHeader(ctx)
-	if err != nil {
-		log.Warn().
-			Err(err).
-			Msg("could not extract authorization header")
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
-	}
+	ctx = withRequestID(ctx)
 
-	rawToken, err := extractBearerToken(header)
+	rawToken, err := extractRawToken(ctx)
 	if err != nil {
-		log.Warn().
-			Err(err).
-			Msg("invalid authorization header")
-		return nil, status.Error(codes.Unauthenticated, "invalid authorization header")
+		i.enforcer.DenyUnauthenticated(
+			ctx,
+			fullMethod,
+			errors.New(status.Convert(err).Message()),
+		)
+
+		return nil, err
 	}
 
-	token, err := i.enforcer.Authorize(ctx, rawToken, fullMethod, req)
-	if err != nil {
+	token, decision := i.enforcer.Decide(ctx, rawToken, fullMethod, req)
+	if !decision.Allowed {
 		log.Warn().
-			Err(err).
+			Err(decision.Err).
 			Str("fullMethod", fullMethod).
+			Object("decision", &decision).
 			Msg("access to resource was denied")
 
 		return nil, status.Error(codes.PermissionDenied, "access denied")
@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/real-evolution/recloak/authz"
+	"github.com/real-evolution/recloak/internal/testrealm"
+)
+
+func TestUnaryInterceptorAuditsRejectedTokens(t *testing.T) {
+	realm := testrealm.NewServer(t)
+
+	enforcer, err := authz.NewEnforcer(realm.Client(t), &authz.AuthzConfig{PathSeparator: "/"})
+	require.NoError(t, err)
+
+	var records []authz.AuditRecord
+	enforcer.SetAuditSink(authz.AuditSinkFunc(func(_ context.Context, record authz.AuditRecord) error {
+		records = append(records, record)
+		return nil
+	}))
+
+	interceptor := NewGrpcInterceptor(enforcer)
+
+	for _, md := range []metadata.MD{
+		metadata.Pairs("x-request-id", "request"),
+		metadata.Pairs("x-request-id", "request", "authorization", "Basic dXNlcjpwYXNz"),
+	} {
+		_, err := interceptor.Unary()(
+			metadata.NewIncomingContext(context.Background(), md),
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "/pkg.Chat/Get"},
+			func(context.Context, any) (any, error) {
+				t.Fatal("handler called")
+				return nil, nil
+			},
+		)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	require.Len(t, records, 2)
+	require.Equal(t, "missing authorization header", records[0].Reason)
+	require.Equal(t, "invalid authorization header", records[1].Reason)
+
+	for _, record := range records {
+		require.False(t, record.Allowed)
+		require.Equal(t, "/pkg.Chat/Get", record.Path)
+		require.Equal(t, "request", record.RequestID)
+	}
+}
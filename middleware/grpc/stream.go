@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+)
+
+// AuthorizedServerStream is a server stream that authorizes each message in
+// the configured direction(s) against the policy of the called method, with
+// the message as the request. The caller is authenticated once, when the
+// stream is opened, and every message is decided from its claims.
+type AuthorizedServerStream struct {
+	*WrappedServerStream
+
+	enforcer    *authz.Enforcer
+	accessToken string
+	token       recloak.Token
+	fullMethod  string
+	direction   authz.StreamDirection
+}
+
+// RecvMsg receives a message and authorizes it if received messages are
+// checked, returning a `PermissionDenied` error if it is denied.
+func (s *AuthorizedServerStream) RecvMsg(m any) error {
+	if err := s.WrappedServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.direction.Recv() {
+		return s.authorizeMessage(m)
+	}
+
+	return nil
+}
+
+// SendMsg authorizes a message if sent messages are checked, and sends it
+// only if it is allowed.
+func (s *AuthorizedServerStream) SendMsg(m any) error {
+	if s.direction.Send() {
+		if err := s.authorizeMessage(m); err != nil {
+			return err
+		}
+	}
+
+	return s.WrappedServerStream.SendMsg(m)
+}
+
+func (s *AuthorizedServerStream) authorizeMessage(m any) error {
+	decision := s.enforcer.DecideMessage(
+		s.Context(),
+		s.accessToken,
+		s.token,
+		s.fullMethod,
+		m,
+	)
+	if !decision.Allowed {
+		log.Warn().
+			Err(decision.Err).
+			Str("fullMethod", s.fullMethod).
+			Object("decision", &decision).
+			Msg("access to stream message was denied")
+
+		return status.Error(codes.PermissionDenied, "access denied")
+	}
+
+	return nil
+}
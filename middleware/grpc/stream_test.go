@@ -0,0 +1,242 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+	"github.com/real-evolution/recloak/internal/testrealm"
+)
+
+type testMessage struct {
+	TenantID string
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	recv []testMessage
+	sent []testMessage
+}
+
+func (s *testServerStream) RecvMsg(m any) error {
+	*m.(*testMessage) = s.recv[0]
+	s.recv = s.recv[1:]
+
+	return nil
+}
+
+func (s *testServerStream) SendMsg(m any) error {
+	s.sent = append(s.sent, *m.(*testMessage))
+
+	return nil
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestAuthorizedServerStream(t *testing.T) {
+	realm := testrealm.NewServer(t)
+
+	config := authz.AuthzConfig{
+		PathSeparator: "/",
+		Resources: []authz.Resource{
+			{
+				Name: "/pkg.Chat",
+				Children: []authz.Resource{
+					{
+						Name: "Talk",
+						Policy: &authz.PolicySpec{
+							InPlace: &authz.Policy{
+								Expression: `Request.TenantID == Claim("tenant")`,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	enforcer, err := authz.NewEnforcer(realm.Client(t), &config)
+	require.NoError(t, err)
+
+	var records []authz.AuditRecord
+	enforcer.SetAuditSink(authz.AuditSinkFunc(func(_ context.Context, record authz.AuditRecord) error {
+		records = append(records, record)
+		return nil
+	}))
+
+	accessToken := realm.Token(t, jwt.MapClaims{"tenant": "acme"})
+
+	token, err := enforcer.Authenticate(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	newStream := func(direction authz.StreamDirection) (*AuthorizedServerStream, *testServerStream) {
+		inner := &testServerStream{
+			recv: []testMessage{{TenantID: "acme"}, {TenantID: "other"}},
+			ctx:  context.Background(),
+		}
+
+		return &AuthorizedServerStream{
+			WrappedServerStream: &WrappedServerStream{inner, inner.ctx},
+			enforcer:            enforcer,
+			accessToken:         accessToken,
+			token:               token,
+			fullMethod:          "/pkg.Chat/Talk",
+			direction:           direction,
+		}, inner
+	}
+
+	t.Run("recv", func(t *testing.T) {
+		stream, inner := newStream(authz.StreamDirectionRecv)
+
+		var msg testMessage
+		require.NoError(t, stream.RecvMsg(&msg))
+
+		err := stream.RecvMsg(&msg)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		require.NoError(t, stream.SendMsg(&testMessage{TenantID: "other"}))
+		require.Len(t, inner.sent, 1)
+	})
+
+	t.Run("send", func(t *testing.T) {
+		stream, inner := newStream(authz.StreamDirectionSend)
+
+		var msg testMessage
+		require.NoError(t, stream.RecvMsg(&msg))
+		require.NoError(t, stream.RecvMsg(&msg))
+
+		require.NoError(t, stream.SendMsg(&testMessage{TenantID: "acme"}))
+
+		err := stream.SendMsg(&testMessage{TenantID: "other"})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.Equal(t, []testMessage{{TenantID: "acme"}}, inner.sent)
+	})
+
+	t.Run("audited", func(t *testing.T) {
+		require.Len(t, records, 4)
+		require.False(t, records[1].Allowed)
+	})
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	realm := testrealm.NewServer(t)
+
+	var introspections atomic.Int32
+	realm.Mux.HandleFunc(
+		"/realms/"+testrealm.Realm+"/protocol/openid-connect/token/introspect",
+		func(w http.ResponseWriter, r *http.Request) {
+			introspections.Add(1)
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"active": true}`))
+		},
+	)
+
+	send := authz.StreamDirectionSend
+
+	config := authz.AuthzConfig{
+		PathSeparator:     "/",
+		IntrospectionMode: authz.IntrospectionModeAlways,
+		Resources: []authz.Resource{
+			{
+				Name: "/pkg.Chat",
+				Children: []authz.Resource{
+					{
+						Name:            "Talk",
+						StreamDirection: &send,
+						Policy: &authz.PolicySpec{
+							InPlace: &authz.Policy{Expression: "Request.TenantID == 'acme'"},
+						},
+					},
+					{
+						Name: "Closed",
+						Policy: &authz.PolicySpec{
+							InPlace: &authz.Policy{Expression: "false"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	enforcer, err := authz.NewEnforcer(realm.Client(t), &config)
+	require.NoError(t, err)
+
+	interceptor := NewGrpcInterceptor(enforcer)
+
+	open := func(
+		fullMethod string,
+		accessToken string,
+		messages ...testMessage,
+	) (bool, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			"authorization", "Bearer "+accessToken,
+		))
+
+		called := false
+		err := interceptor.Stream()(
+			nil,
+			&testServerStream{ctx: ctx},
+			&grpc.StreamServerInfo{FullMethod: fullMethod},
+			func(_ any, stream grpc.ServerStream) error {
+				called = true
+
+				_, err := recloak.TokenFromContext(stream.Context())
+				require.NoError(t, err)
+
+				for _, msg := range messages {
+					if err := stream.SendMsg(&msg); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+		)
+
+		return called, err
+	}
+
+	accessToken := realm.Token(t, nil)
+
+	t.Run("opening without messages", func(t *testing.T) {
+		called, err := open("/pkg.Chat/Closed", accessToken)
+		require.False(t, called)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("authenticated once", func(t *testing.T) {
+		introspections.Store(0)
+
+		called, err := open(
+			"/pkg.Chat/Talk",
+			accessToken,
+			testMessage{TenantID: "acme"},
+			testMessage{TenantID: "acme"},
+			testMessage{TenantID: "other"},
+		)
+		require.True(t, called)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.EqualValues(t, 1, introspections.Load())
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		called, err := open("/pkg.Chat/Talk", "invalid")
+		require.False(t, called)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
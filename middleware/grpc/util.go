@@ -4,7 +4,10 @@ import (
 	"context"
 	"strings"
 
+	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/metadata"
+
+	"github.com/real-evolution/recloak/authz"
 )
 
 func extractAuthorizationHeader(ctx context.Context) (string, error) {
@@ -22,6 +25,28 @@ func extractAuthorizationHeader(ctx context.Context) (string, error) {
 	return values[0], nil
 }
 
+// extractRawToken returns the bearer token of the incoming metadata, or one of
+// the `Unauthenticated` errors of the package if it is missing or malformed.
+func extractRawToken(ctx context.Context) (string, error) {
+	header, err := extractAuthorizationHeader(ctx)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Msg("could not extract authorization header")
+		return "", err
+	}
+
+	rawToken, err := extractBearerToken(header)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Msg("invalid authorization header")
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
 func extractBearerToken(header string) (string, error) {
 	if len(header) < 7 || strings.ToLower(header[:6]) != "bearer" {
 		return "", ErrInvalidAuthorizationHeader
@@ -29,3 +54,18 @@ func extractBearerToken(header string) (string, error) {
 
 	return header[7:], nil
 }
+
+// withRequestID returns a context carrying the request ID from the incoming
+// `x-request-id` metadata, if any, for the audit log.
+func withRequestID(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	if values := md.Get("x-request-id"); len(values) > 0 && values[0] != "" {
+		return authz.WithRequestID(ctx, values[0])
+	}
+
+	return ctx
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Bearer token error codes, as defined in RFC 6750, section 3.1.
+const (
+	ErrorCodeInvalidRequest    = "invalid_request"
+	ErrorCodeInvalidToken      = "invalid_token"
+	ErrorCodeInsufficientScope = "insufficient_scope"
+)
+
+var (
+	// ErrMissingAuthorizationHeader is returned when the authorization header
+	// is missing from the request.
+	ErrMissingAuthorizationHeader = errors.New("missing authorization header")
+
+	// ErrInvalidAuthorizationHeader is returned when the authorization header
+	// is invalid.
+	ErrInvalidAuthorizationHeader = errors.New("invalid authorization header")
+)
+
+// BearerError is an error response of a resource server protected by bearer
+// tokens, as defined in RFC 6750.
+type BearerError struct {
+	// The HTTP status code of the response.
+	Status int
+
+	// The error code, empty if the request lacks any authentication
+	// information.
+	Code string
+
+	// A human-readable description of the error.
+	Description string
+}
+
+func (e *BearerError) Error() string {
+	if e.Code == "" {
+		return e.Description
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// Challenge returns the value of the `WWW-Authenticate` header of the error
+// response for the given realm.
+func (e *BearerError) Challenge(realm string) string {
+	params := make([]string, 0, 3)
+
+	if realm != "" {
+		params = append(params, fmt.Sprintf("realm=%s", quote(realm)))
+	}
+
+	if e.Code != "" {
+		params = append(params, fmt.Sprintf("error=%s", quote(e.Code)))
+	}
+
+	if e.Code != "" && e.Description != "" {
+		params = append(
+			params,
+			fmt.Sprintf("error_description=%s", quote(e.Description)),
+		)
+	}
+
+	if len(params) == 0 {
+		return "Bearer"
+	}
+
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// Write writes the error response with the challenge for the given realm.
+// Server errors are written without a challenge, as they are not caused by the
+// credentials of the request.
+func (e *BearerError) Write(w http.ResponseWriter, realm string) {
+	if e.Status < http.StatusInternalServerError {
+		w.Header().Set("WWW-Authenticate", e.Challenge(realm))
+	}
+
+	http.Error(w, http.StatusText(e.Status), e.Status)
+}
+
+func newMissingTokenError() *BearerError {
+	return &BearerError{
+		Status:      http.StatusUnauthorized,
+		Description: "missing access token",
+	}
+}
+
+func newInvalidRequestError(description string) *BearerError {
+	return &BearerError{
+		Status:      http.StatusBadRequest,
+		Code:        ErrorCodeInvalidRequest,
+		Description: description,
+	}
+}
+
+func newInvalidTokenError(description string) *BearerError {
+	return &BearerError{
+		Status:      http.StatusUnauthorized,
+		Code:        ErrorCodeInvalidToken,
+		Description: description,
+	}
+}
+
+func newInsufficientScopeError(description string) *BearerError {
+	return &BearerError{
+		Status:      http.StatusForbidden,
+		Code:        ErrorCodeInsufficientScope,
+		Description: description,
+	}
+}
+
+func newUnavailableError() *BearerError {
+	return &BearerError{
+		Status:      http.StatusServiceUnavailable,
+		Description: "authorization is unavailable",
+	}
+}
+
+func newInternalError() *BearerError {
+	return &BearerError{
+		Status:      http.StatusInternalServerError,
+		Description: "could not authorize request",
+	}
+}
+
+// quote quotes the given value as an HTTP quoted-string.
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+
+	return `"` + value + `"`
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+)
+
+// LogoutTokenDecoder is a type that decodes and validates back-channel logout
+// tokens, e.g. `recloak.ReCloak`.
+type LogoutTokenDecoder interface {
+	// DecodeLogoutToken decodes and validates a logout token.
+	DecodeLogoutToken(ctx context.Context, tokenString string) (*recloak.LogoutClaims, error)
+}
+
+// BackChannelLogoutHandler is an HTTP handler that receives OIDC back-channel
+// logout requests from keycloak, and records the logged out sessions in a
+// revocation store.
+//
+// The session of the logout token is revoked if it has one, otherwise its
+// subject is revoked, along with all tokens issued before the logout.
+type BackChannelLogoutHandler struct {
+	decoder LogoutTokenDecoder
+	store   authz.RevocationStore
+}
+
+// NewBackChannelLogoutHandler creates a new back-channel logout handler.
+func NewBackChannelLogoutHandler(
+	decoder LogoutTokenDecoder,
+	store authz.RevocationStore,
+) *BackChannelLogoutHandler {
+	return &BackChannelLogoutHandler{decoder: decoder, store: store}
+}
+
+func (h *BackChannelLogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	rawToken := r.PostFormValue("logout_token")
+	if rawToken == "" {
+		writeLogoutError(w, http.StatusBadRequest, "missing logout token")
+		return
+	}
+
+	claims, err := h.decoder.DecodeLogoutToken(r.Context(), rawToken)
+	if err != nil {
+		log.Warn().Err(err).Msg("received an invalid logout token")
+		writeLogoutError(w, http.StatusBadRequest, "invalid logout token")
+		return
+	}
+
+	revokedAt := time.Now()
+	if claims.IssuedAt != nil {
+		revokedAt = claims.IssuedAt.Time
+	}
+
+	if claims.SessionID != "" {
+		err = h.store.RevokeSession(r.Context(), claims.SessionID, revokedAt)
+	} else {
+		err = h.store.RevokeSubject(r.Context(), claims.Subject, revokedAt)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("could not record logout")
+		writeLogoutError(w, http.StatusInternalServerError, "could not record logout")
+		return
+	}
+
+	log.Debug().
+		Str("sid", claims.SessionID).
+		Str("sub", claims.Subject).
+		Msg("recorded back-channel logout")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeLogoutError writes an error response, as defined in the OIDC
+// back-channel logout specification.
+func writeLogoutError(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":             ErrorCodeInvalidRequest,
+		"error_description": description,
+	})
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+)
+
+type testLogoutTokenDecoder map[string]*recloak.LogoutClaims
+
+func (d testLogoutTokenDecoder) DecodeLogoutToken(
+	_ context.Context,
+	tokenString string,
+) (*recloak.LogoutClaims, error) {
+	if claims, ok := d[tokenString]; ok {
+		return claims, nil
+	}
+
+	return nil, recloak.ErrInvalidLogoutToken
+}
+
+func TestBackChannelLogoutHandler(t *testing.T) {
+	issuedAt := time.Now().Truncate(time.Second)
+
+	decoder := testLogoutTokenDecoder{
+		"session-token": {
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:  "user",
+				IssuedAt: jwt.NewNumericDate(issuedAt),
+			},
+			SessionID: "session",
+		},
+		"subject-token": {
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:  "other",
+				IssuedAt: jwt.NewNumericDate(issuedAt),
+			},
+		},
+	}
+
+	store := authz.NewMemoryRevocationStore(time.Hour)
+	handler := NewBackChannelLogoutHandler(decoder, store)
+
+	logout := func(method, token string) *httptest.ResponseRecorder {
+		form := url.Values{}
+		if token != "" {
+			form.Set("logout_token", token)
+		}
+
+		r := httptest.NewRequest(method, "/logout", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	isRevoked := func(sessionID, subject string) bool {
+		revoked, err := store.IsRevoked(
+			context.Background(),
+			sessionID,
+			subject,
+			issuedAt.Add(-time.Second),
+		)
+		require.NoError(t, err)
+
+		return revoked
+	}
+
+	t.Run("session", func(t *testing.T) {
+		w := logout(http.MethodPost, "session-token")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		require.True(t, isRevoked("session", ""))
+		require.False(t, isRevoked("", "user"))
+	})
+
+	t.Run("subject", func(t *testing.T) {
+		w := logout(http.MethodPost, "subject-token")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.True(t, isRevoked("", "other"))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, logout(http.MethodPost, "").Code)
+		require.Equal(t, http.StatusBadRequest, logout(http.MethodPost, "forged").Code)
+		require.Equal(t, http.StatusMethodNotAllowed, logout(http.MethodGet, "").Code)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		handler := NewBackChannelLogoutHandler(decoder, failingRevocationStore{})
+
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/logout",
+			strings.NewReader("logout_token=session-token"),
+		)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+type failingRevocationStore struct {
+	authz.RevocationStore
+}
+
+func (failingRevocationStore) RevokeSession(context.Context, string, time.Time) error {
+	return errors.New("store is down")
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+)
+
+// PathResolver is a function that resolves the authorization resource path of
+// a request, given the configured path separator.
+type PathResolver func(r *http.Request, separator string) string
+
+// Middleware is a net/http middleware that checks requests against the
+// enforcer.
+type Middleware struct {
+	enforcer     *authz.Enforcer
+	pathResolver PathResolver
+}
+
+// NewHttpMiddleware creates a new net/http middleware.
+func NewHttpMiddleware(e *authz.Enforcer) Middleware {
+	return Middleware{
+		enforcer:     e,
+		pathResolver: DefaultPathResolver,
+	}
+}
+
+// WithPathResolver returns a copy of the middleware that resolves resource
+// paths using the given resolver.
+func (m Middleware) WithPathResolver(resolver PathResolver) Middleware {
+	m.pathResolver = resolver
+
+	return m
+}
+
+// Handler returns a handler that performs authorization on requests before
+// passing them to the given handler.
+//
+// The decoded token is stored in the request context, and can be retrieved
+// with `recloak.TokenFromContext`.
+func (m Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, bearerErr := m.authorize(r)
+		if bearerErr != nil {
+			bearerErr.Write(w, m.enforcer.Client().Config().Realm)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m Middleware) authorize(r *http.Request) (*http.Request, *BearerError) {
+	path := m.pathResolver(r, m.enforcer.Config().PathSeparator)
+
+	log.Debug().Str("path", path).Msg("authorizing request")
+
+	r = r.WithContext(withRequestID(r))
+
+	rawToken, err := extractBearerToken(r)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("path", path).
+			Msg("could not extract bearer token")
+
+		m.enforcer.DenyUnauthenticated(r.Context(), path, err)
+
+		if errors.Is(err, ErrMissingAuthorizationHeader) {
+			return nil, newMissingTokenError()
+		}
+
+		return nil, newInvalidRequestError(err.Error())
+	}
+
+	token, decision := m.enforcer.Decide(r.Context(), rawToken, path, r)
+	if !decision.Allowed {
+		log.Warn().
+			Err(decision.Err).
+			Str("path", path).
+			Object("decision", &decision).
+			Msg("access to resource was denied")
+
+		switch {
+		case errors.Is(decision.Err, authz.ErrUnauthorized),
+			errors.Is(decision.Err, authz.ErrorNoPolicyForPath):
+			return nil, newInsufficientScopeError("access denied")
+
+		case errors.Is(decision.Err, recloak.ErrInvalidToken),
+			errors.Is(decision.Err, authz.ErrTokenRevoked):
+			return nil, newInvalidTokenError("invalid access token")
+
+		case errors.Is(decision.Err, authz.ErrUnavailable):
+			return nil, newUnavailableError()
+
+		default:
+			return nil, newInternalError()
+		}
+	}
+
+	return r.WithContext(token.WrapContext(r.Context())), nil
+}
+
+// DefaultPathResolver resolves the resource path of a request from its route
+// followed by its method, e.g. `/tenants/{tenant}/docs/GET` when the separator
+// is `/`.
+//
+// The route is the matched `http.ServeMux` pattern if the middleware wraps a
+// registered handler, or the request URL path otherwise.
+func DefaultPathResolver(r *http.Request, separator string) string {
+	route := strings.TrimRight(routeOf(r), "/")
+
+	return route + separator + r.Method
+}
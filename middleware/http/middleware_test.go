@@ -0,0 +1,314 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/real-evolution/recloak"
+	"github.com/real-evolution/recloak/authz"
+	"github.com/real-evolution/recloak/internal/testrealm"
+)
+
+func TestDefaultPathResolver(t *testing.T) {
+	t.Run("request path", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/tenants/acme/docs/", nil)
+
+		require.Equal(t, "/tenants/acme/docs/GET", DefaultPathResolver(r, "/"))
+	})
+
+	t.Run("root path", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+		require.Equal(t, "/POST", DefaultPathResolver(r, "/"))
+	})
+
+	t.Run("matched pattern", func(t *testing.T) {
+		var actual string
+
+		mux := http.NewServeMux()
+		mux.HandleFunc(
+			"DELETE example.com/tenants/{tenant}/docs/{doc}",
+			func(w http.ResponseWriter, r *http.Request) {
+				actual = DefaultPathResolver(r, "/")
+			},
+		)
+
+		r := httptest.NewRequest(
+			http.MethodDelete,
+			"http://example.com/tenants/acme/docs/1",
+			nil,
+		)
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+
+		require.Equal(t, "/tenants/{tenant}/docs/{doc}/DELETE", actual)
+	})
+}
+
+func TestExtractBearerToken(t *testing.T) {
+	testData := []struct {
+		header   string
+		expected string
+		err      error
+	}{
+		{header: "", err: ErrMissingAuthorizationHeader},
+		{header: "Basic dXNlcjpwYXNz", err: ErrInvalidAuthorizationHeader},
+		{header: "Bearer", err: ErrInvalidAuthorizationHeader},
+		{header: "Bearer  ", err: ErrInvalidAuthorizationHeader},
+		{header: "Bearer some.jwt.token", expected: "some.jwt.token"},
+		{header: "bearer some.jwt.token", expected: "some.jwt.token"},
+	}
+
+	for _, data := range testData {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if data.header != "" {
+			r.Header.Set("Authorization", data.header)
+		}
+
+		actual, err := extractBearerToken(r)
+		require.ErrorIs(t, err, data.err)
+		require.Equal(t, data.expected, actual)
+	}
+}
+
+func TestBearerErrorResponse(t *testing.T) {
+	testData := []struct {
+		err       *BearerError
+		status    int
+		challenge string
+	}{
+		{
+			err:       newMissingTokenError(),
+			status:    http.StatusUnauthorized,
+			challenge: `Bearer realm="test"`,
+		},
+		{
+			err:    newInvalidRequestError("invalid authorization header"),
+			status: http.StatusBadRequest,
+			challenge: `Bearer realm="test", error="invalid_request", ` +
+				`error_description="invalid authorization header"`,
+		},
+		{
+			err:    newInvalidTokenError(`token "expired"`),
+			status: http.StatusUnauthorized,
+			challenge: `Bearer realm="test", error="invalid_token", ` +
+				`error_description="token \"expired\""`,
+		},
+		{
+			err:    newInsufficientScopeError("access denied"),
+			status: http.StatusForbidden,
+			challenge: `Bearer realm="test", error="insufficient_scope", ` +
+				`error_description="access denied"`,
+		},
+		{
+			err:    newUnavailableError(),
+			status: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, data := range testData {
+		w := httptest.NewRecorder()
+		data.err.Write(w, "test")
+
+		require.Equal(t, data.status, w.Code)
+		require.Equal(t, data.challenge, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestHandlerAuditsRejectedTokens(t *testing.T) {
+	realm := testrealm.NewServer(t)
+
+	config := authz.AuthzConfig{
+		PathSeparator: "/",
+		Resources: []authz.Resource{
+			{
+				Name:   "/docs/GET",
+				Policy: &authz.PolicySpec{InPlace: &authz.Policy{Expression: "true"}},
+			},
+		},
+	}
+
+	enforcer, err := authz.NewEnforcer(realm.Client(t), &config)
+	require.NoError(t, err)
+
+	var records []authz.AuditRecord
+	enforcer.SetAuditSink(authz.AuditSinkFunc(func(_ context.Context, record authz.AuditRecord) error {
+		records = append(records, record)
+		return nil
+	}))
+
+	handler := NewHttpMiddleware(enforcer).Handler(http.NotFoundHandler())
+
+	for _, header := range []string{"", "Basic dXNlcjpwYXNz"} {
+		r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		r.Header.Set("X-Request-ID", "request")
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	require.Len(t, records, 2)
+	for i, reason := range []string{
+		ErrMissingAuthorizationHeader.Error(),
+		ErrInvalidAuthorizationHeader.Error(),
+	} {
+		require.False(t, records[i].Allowed)
+		require.Equal(t, "/docs/GET", records[i].Path)
+		require.Equal(t, "request", records[i].RequestID)
+		require.Equal(t, reason, records[i].Reason)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	realm := testrealm.NewServer(t)
+	realm.Mux.HandleFunc(
+		"/realms/"+testrealm.Realm+"/protocol/openid-connect/token/introspect",
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		},
+	)
+
+	newHandler := func(config authz.AuthzConfig) http.Handler {
+		config.PathSeparator = "/"
+		config.Resources = []authz.Resource{
+			{
+				Name:   "/docs/GET",
+				Policy: &authz.PolicySpec{InPlace: &authz.Policy{Expression: "true"}},
+			},
+			{
+				Name:   "/admin/GET",
+				Policy: &authz.PolicySpec{InPlace: &authz.Policy{Expression: "false"}},
+			},
+		}
+
+		enforcer, err := authz.NewEnforcer(realm.Client(t), &config)
+		require.NoError(t, err)
+
+		return NewHttpMiddleware(enforcer).Handler(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := recloak.TokenFromContext(r.Context())
+				require.NoError(t, err)
+			}),
+		)
+	}
+
+	handler := newHandler(authz.AuthzConfig{})
+	introspecting := newHandler(authz.AuthzConfig{
+		IntrospectionMode: authz.IntrospectionModeAlways,
+	})
+
+	token := realm.Token(t, nil)
+	expired := realm.Token(t, jwt.MapClaims{
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+
+	testData := []struct {
+		handler   http.Handler
+		path      string
+		header    string
+		status    int
+		challenge string
+	}{
+		{
+			handler:   handler,
+			path:      "/docs",
+			status:    http.StatusUnauthorized,
+			challenge: `Bearer realm="test"`,
+		},
+		{
+			handler: handler,
+			path:    "/docs",
+			header:  "Basic dXNlcjpwYXNz",
+			status:  http.StatusBadRequest,
+			challenge: `Bearer realm="test", error="invalid_request", ` +
+				`error_description="invalid authorization header"`,
+		},
+		{
+			handler: handler,
+			path:    "/docs",
+			header:  "Bearer " + expired,
+			status:  http.StatusUnauthorized,
+			challenge: `Bearer realm="test", error="invalid_token", ` +
+				`error_description="invalid access token"`,
+		},
+		{
+			handler: handler,
+			path:    "/docs",
+			header:  "Bearer not.a.token",
+			status:  http.StatusUnauthorized,
+			challenge: `Bearer realm="test", error="invalid_token", ` +
+				`error_description="invalid access token"`,
+		},
+		{
+			handler: handler,
+			path:    "/admin",
+			header:  "Bearer " + token,
+			status:  http.StatusForbidden,
+			challenge: `Bearer realm="test", error="insufficient_scope", ` +
+				`error_description="access denied"`,
+		},
+		{
+			handler: handler,
+			path:    "/docs",
+			header:  "Bearer " + token,
+			status:  http.StatusOK,
+		},
+		{
+			handler: introspecting,
+			path:    "/docs",
+			header:  "Bearer " + token,
+			status:  http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, data := range testData {
+		r := httptest.NewRequest(http.MethodGet, data.path, nil)
+		if data.header != "" {
+			r.Header.Set("Authorization", data.header)
+		}
+
+		w := httptest.NewRecorder()
+		data.handler.ServeHTTP(w, r)
+
+		require.Equal(t, data.status, w.Code, "%s %s", data.path, data.header)
+		require.Equal(t, data.challenge, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestHandlerKeysUnavailable(t *testing.T) {
+	realm := testrealm.NewServer(t)
+
+	config := authz.AuthzConfig{
+		PathSeparator: "/",
+		Resources: []authz.Resource{
+			{
+				Name:   "/docs/GET",
+				Policy: &authz.PolicySpec{InPlace: &authz.Policy{Expression: "true"}},
+			},
+		},
+	}
+
+	enforcer, err := authz.NewEnforcer(realm.Client(t), &config)
+	require.NoError(t, err)
+
+	handler := NewHttpMiddleware(enforcer).Handler(http.NotFoundHandler())
+
+	token := realm.Token(t, nil)
+	realm.Close()
+
+	r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Empty(t, w.Header().Get("WWW-Authenticate"))
+}
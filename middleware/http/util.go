@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/real-evolution/recloak/authz"
+)
+
+func extractBearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrMissingAuthorizationHeader
+	}
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return "", ErrInvalidAuthorizationHeader
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", ErrInvalidAuthorizationHeader
+	}
+
+	return token, nil
+}
+
+// routeOf returns the route of the request, which is the path of the matched
+// `http.ServeMux` pattern if any, or the request URL path otherwise.
+func routeOf(r *http.Request) string {
+	if r.Pattern == "" {
+		return r.URL.Path
+	}
+
+	route := r.Pattern
+
+	// strip the method and the host from the pattern
+	if _, rest, ok := strings.Cut(route, " "); ok {
+		route = strings.TrimSpace(rest)
+	}
+
+	if idx := strings.Index(route, "/"); idx > 0 {
+		route = route[idx:]
+	}
+
+	return route
+}
+
+// withRequestID returns a context carrying the request ID from the
+// `X-Request-Id` header, if any, for the audit log.
+func withRequestID(r *http.Request) context.Context {
+	if requestID := r.Header.Get("X-Request-Id"); requestID != "" {
+		return authz.WithRequestID(r.Context(), requestID)
+	}
+
+	return r.Context()
+}
@@ -2,7 +2,7 @@ package recloak
 
 import (
 	"context"
-	"time"
+	"sync"
 
 	"github.com/Nerzal/gocloak/v13"
 )
@@ -15,7 +15,15 @@ type APIError = gocloak.APIError
 type ReCloak struct {
 	client *gocloak.GoCloak
 	config *ClientConfig
-	token  *gocloak.JWT
+	keys   *KeySet
+	tokens *ClientTokenSource
+
+	exchanges *exchangeCache
+
+	// IDs of the logout tokens that were seen, to reject replays
+	logoutTokens *seenTokenIDs
+
+	reprMu sync.Mutex
 	repr   *gocloak.Client
 }
 
@@ -23,7 +31,20 @@ type ReCloak struct {
 func NewClient(config *ClientConfig) (*ReCloak, error) {
 	client := gocloak.NewClient(config.AuthServerURL)
 
-	return &ReCloak{client, config, nil, nil}, nil
+	keys, err := NewKeySet(client, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReCloak{
+		client: client,
+		config: config,
+		keys:   keys,
+		tokens: NewClientTokenSource(client, config),
+
+		exchanges:    newExchangeCache(),
+		logoutTokens: newSeenTokenIDs(),
+	}, nil
 }
 
 // Client returns the gocloak client
@@ -36,57 +57,40 @@ func (r *ReCloak) Config() *ClientConfig {
 	return r.config
 }
 
+// Keys returns the realm key set used to verify access tokens
+func (r *ReCloak) Keys() *KeySet {
+	return r.keys
+}
+
+// TokenSource returns the service account token source
+func (r *ReCloak) TokenSource() *ClientTokenSource {
+	return r.tokens
+}
+
 // Token returns the current token
 func (r *ReCloak) Token() *gocloak.JWT {
-	return r.token
+	return r.tokens.Current()
 }
 
 // Login logs in the client
 func (r *ReCloak) Login(ctx context.Context) error {
-	token, err := r.client.LoginClient(
-		ctx,
-		r.config.ClientID,
-		r.config.ClientSecret,
-		r.config.Realm,
-	)
-	if err != nil {
-		return err
-	}
-
-	r.token = token
+	_, err := r.tokens.Login(ctx)
 
-	return nil
+	return err
 }
 
 // Refresh refreshes the token
 func (r *ReCloak) Refresh(ctx context.Context) error {
-	if r.token == nil || r.token.RefreshToken == "" {
-		return r.Login(ctx)
-	}
-
-	token, err := r.client.RefreshToken(
-		ctx,
-		r.token.RefreshToken,
-		r.config.ClientID,
-		r.config.ClientSecret,
-		r.config.Realm,
-	)
-	if err != nil {
-		return err
-	}
+	_, err := r.tokens.Refresh(ctx)
 
-	r.token = token
-
-	return nil
+	return err
 }
 
 // RefreshIfExpired refreshes the token if it is expired
 func (r *ReCloak) RefreshIfExpired(ctx context.Context) error {
-	if r.token == nil || isTimestampExpired(int64(r.token.ExpiresIn)) {
-		return r.Refresh(ctx)
-	}
+	_, err := r.tokens.Token(ctx)
 
-	return nil
+	return err
 }
 
 // Gets client representation from the keycloak server.
@@ -96,6 +100,9 @@ func (r *ReCloak) GetRepresentation(ctx context.Context) (*gocloak.Client, error
 		return nil, err
 	}
 
+	r.reprMu.Lock()
+	defer r.reprMu.Unlock()
+
 	if r.repr != nil {
 		return r.repr, nil
 	}
@@ -118,11 +125,3 @@ func (r *ReCloak) GetRepresentation(ctx context.Context) (*gocloak.Client, error
 
 	return repr, nil
 }
-
-// Checks whether the given timestamp is expired (in the past) or not.
-func isTimestampExpired(timestamp int64) bool {
-	exp := time.Unix(timestamp, 0)
-	now := time.Now()
-
-	return exp.After(now)
-}
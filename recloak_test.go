@@ -0,0 +1,217 @@
+package recloak
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const testRealm = "test"
+
+// testRealmServer is a fake keycloak realm that publishes its signing keys and
+// issues service account tokens.
+type testRealmServer struct {
+	*httptest.Server
+
+	mu               sync.Mutex
+	keys             map[string]*rsa.PrivateKey
+	expiresIn        int
+	refreshExpiresIn int
+	rejectRefresh    bool
+	tokenDelay       time.Duration
+
+	fetches   atomic.Int32
+	logins    atomic.Int32
+	refreshes atomic.Int32
+	exchanges atomic.Int32
+}
+
+func newTestRealmServer(t *testing.T) *testRealmServer {
+	t.Helper()
+
+	s := &testRealmServer{
+		keys:             make(map[string]*rsa.PrivateKey),
+		expiresIn:        300,
+		refreshExpiresIn: 1800,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		"/realms/"+testRealm+"/protocol/openid-connect/certs",
+		s.serveCerts,
+	)
+	mux.HandleFunc(
+		"/realms/"+testRealm+"/protocol/openid-connect/token",
+		s.serveToken,
+	)
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *testRealmServer) serveCerts(w http.ResponseWriter, r *http.Request) {
+	s.fetches.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]map[string]string, 0, len(s.keys))
+	for kid, key := range s.keys {
+		keys = append(keys, map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(
+				big.NewInt(int64(key.E)).Bytes(),
+			),
+		})
+	}
+
+	writeTestJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func (s *testRealmServer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	delay := s.tokenDelay
+	rejectRefresh := s.rejectRefresh
+	expiresIn := s.expiresIn
+	refreshExpiresIn := s.refreshExpiresIn
+	s.mu.Unlock()
+
+	time.Sleep(delay)
+
+	var n int32
+
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		n = s.logins.Add(1)
+
+	case "refresh_token":
+		n = s.refreshes.Add(1)
+
+		if rejectRefresh {
+			writeTestJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid_grant",
+			})
+			return
+		}
+
+	case GrantTypeTokenExchange:
+		n = s.exchanges.Add(1)
+
+		if r.PostForm.Get("subject_token") == "" ||
+			r.PostForm.Get("subject_token_type") == "" {
+			writeTestJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "invalid_request",
+			})
+			return
+		}
+
+		writeTestJSON(w, http.StatusOK, map[string]any{
+			"access_token": fmt.Sprintf(
+				"%s-for-%s-%d",
+				r.PostForm.Get("subject_token"),
+				r.PostForm.Get("audience"),
+				n,
+			),
+			"expires_in": expiresIn,
+			"token_type": "Bearer",
+		})
+		return
+
+	default:
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "unsupported_grant_type",
+		})
+		return
+	}
+
+	writeTestJSON(w, http.StatusOK, map[string]any{
+		"access_token":       fmt.Sprintf("%s-%d", r.PostForm.Get("grant_type"), n),
+		"expires_in":         expiresIn,
+		"refresh_expires_in": refreshExpiresIn,
+		"refresh_token":      fmt.Sprintf("refresh-%d", n),
+		"token_type":         "Bearer",
+	})
+}
+
+// rotate replaces the published keys with a new key with the given ID.
+func (s *testRealmServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = map[string]*rsa.PrivateKey{kid: key}
+
+	return key
+}
+
+func (s *testRealmServer) config() *ClientConfig {
+	return &ClientConfig{
+		AuthServerURL: s.URL,
+		Realm:         testRealm,
+		ClientID:      "client",
+		ClientSecret:  "secret",
+	}
+}
+
+func (s *testRealmServer) claims() *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.URL + "/realms/" + testRealm,
+			Subject:   "user",
+			Audience:  jwt.ClaimStrings{"client"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		PreferredUsername: "username",
+	}
+}
+
+func signTestToken(
+	t *testing.T,
+	key *rsa.PrivateKey,
+	kid string,
+	claims jwt.Claims,
+) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,226 @@
+package recloak
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultTokenExpiryDelta is how long before its expiry a service account
+// token is considered expired and gets refreshed.
+const DefaultTokenExpiryDelta = 30 * time.Second
+
+// TokenSource is a type that supplies valid service account tokens.
+type TokenSource interface {
+	// Token returns a token that is valid for at least the expiry delta of
+	// the source, refreshing it if needed.
+	Token(ctx context.Context) (*gocloak.JWT, error)
+}
+
+// ClientTokenSource is a `TokenSource` that obtains service account tokens
+// using the client credentials grant. It is safe for concurrent use.
+//
+// Tokens are refreshed using the refresh token while it is valid, and a full
+// login is performed otherwise. Concurrent refreshes are coalesced into a
+// single request.
+type ClientTokenSource struct {
+	client      *gocloak.GoCloak
+	config      *ClientConfig
+	expiryDelta time.Duration
+	now         func() time.Time
+
+	mu               sync.RWMutex
+	token            *gocloak.JWT
+	expiresAt        time.Time
+	refreshExpiresAt time.Time
+
+	// lifetimes of the current token and of its refresh token
+	lifetime        time.Duration
+	refreshLifetime time.Duration
+
+	refreshMu sync.Mutex
+}
+
+// NewClientTokenSource creates a new client credentials token source.
+func NewClientTokenSource(
+	client *gocloak.GoCloak,
+	config *ClientConfig,
+) *ClientTokenSource {
+	return &ClientTokenSource{
+		client:      client,
+		config:      config,
+		expiryDelta: DefaultTokenExpiryDelta,
+		now:         time.Now,
+	}
+}
+
+// SetExpiryDelta sets how long before its expiry a token gets refreshed. The
+// delta is clamped to half of the lifetime of shorter-lived tokens.
+func (s *ClientTokenSource) SetExpiryDelta(delta time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expiryDelta = delta
+}
+
+// Token returns the current token, refreshing it if it is about to expire.
+func (s *ClientTokenSource) Token(ctx context.Context) (*gocloak.JWT, error) {
+	if token, ok := s.valid(); ok {
+		return token, nil
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	// another caller refreshed the token while we were waiting
+	if token, ok := s.valid(); ok {
+		return token, nil
+	}
+
+	return s.refreshLocked(ctx)
+}
+
+// Current returns the current token without refreshing it, or nil if no token
+// was obtained yet.
+func (s *ClientTokenSource) Current() *gocloak.JWT {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.token
+}
+
+// ExpiresAt returns the expiry time of the current token.
+func (s *ClientTokenSource) ExpiresAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.expiresAt
+}
+
+// Login obtains a new token using the client credentials grant, regardless
+// of the current token.
+func (s *ClientTokenSource) Login(ctx context.Context) (*gocloak.JWT, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	return s.loginLocked(ctx)
+}
+
+// Refresh obtains a new token, regardless of the current token expiry.
+func (s *ClientTokenSource) Refresh(ctx context.Context) (*gocloak.JWT, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	return s.refreshLocked(ctx)
+}
+
+// Run keeps the token fresh by refreshing it ahead of its expiry until the
+// given context is done.
+func (s *ClientTokenSource) Run(ctx context.Context) error {
+	const retryInterval = 5 * time.Second
+
+	for {
+		wait := retryInterval
+
+		if _, err := s.Token(ctx); err != nil {
+			log.Warn().Err(err).Msg("could not refresh service account token")
+		} else {
+			s.mu.RLock()
+			wait = s.expiresAt.Sub(s.now()) - s.deltaLocked(s.lifetime)
+			s.mu.RUnlock()
+		}
+
+		timer := time.NewTimer(max(wait, time.Second))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+
+		case <-timer.C:
+		}
+	}
+}
+
+// valid returns the current token if it is not about to expire.
+func (s *ClientTokenSource) valid() (*gocloak.JWT, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.token == nil || !s.now().Add(s.deltaLocked(s.lifetime)).Before(s.expiresAt) {
+		return nil, false
+	}
+
+	return s.token, true
+}
+
+func (s *ClientTokenSource) refreshLocked(ctx context.Context) (*gocloak.JWT, error) {
+	s.mu.RLock()
+	current := s.token
+	canRefresh := current != nil &&
+		current.RefreshToken != "" &&
+		s.now().Add(s.deltaLocked(s.refreshLifetime)).Before(s.refreshExpiresAt)
+	s.mu.RUnlock()
+
+	if !canRefresh {
+		return s.loginLocked(ctx)
+	}
+
+	issuedAt := s.now()
+	token, err := s.client.RefreshToken(
+		ctx,
+		current.RefreshToken,
+		s.config.ClientID,
+		s.config.ClientSecret,
+		s.config.Realm,
+	)
+	if err != nil {
+		log.Debug().Err(err).Msg("could not refresh token, logging in again")
+
+		return s.loginLocked(ctx)
+	}
+
+	s.store(token, issuedAt)
+
+	return token, nil
+}
+
+func (s *ClientTokenSource) loginLocked(ctx context.Context) (*gocloak.JWT, error) {
+	issuedAt := s.now()
+	token, err := s.client.LoginClient(
+		ctx,
+		s.config.ClientID,
+		s.config.ClientSecret,
+		s.config.Realm,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.store(token, issuedAt)
+
+	return token, nil
+}
+
+// store stores the given token, computing its absolute expiry times relative
+// to when it was requested.
+func (s *ClientTokenSource) store(token *gocloak.JWT, issuedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = token
+	s.lifetime = time.Duration(token.ExpiresIn) * time.Second
+	s.refreshLifetime = time.Duration(token.RefreshExpiresIn) * time.Second
+	s.expiresAt = issuedAt.Add(s.lifetime)
+	s.refreshExpiresAt = issuedAt.Add(s.refreshLifetime)
+}
+
+// deltaLocked returns the expiry delta of a token with the given lifetime,
+// which is clamped to half of the lifetime, so that tokens that live shorter
+// than the expiry delta are still reused.
+func (s *ClientTokenSource) deltaLocked(lifetime time.Duration) time.Duration {
+	return min(s.expiryDelta, lifetime/2)
+}
@@ -0,0 +1,157 @@
+package recloak
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTokenSourceCachesToken(t *testing.T) {
+	server := newTestRealmServer(t)
+	client, err := NewClient(server.config())
+	require.NoError(t, err)
+
+	require.Nil(t, client.Token())
+
+	for range 3 {
+		require.NoError(t, client.RefreshIfExpired(context.Background()))
+	}
+
+	require.Equal(t, "client_credentials-1", client.Token().AccessToken)
+	require.EqualValues(t, 1, server.logins.Load())
+	require.WithinDuration(
+		t,
+		time.Now().Add(300*time.Second),
+		client.TokenSource().ExpiresAt(),
+		5*time.Second,
+	)
+}
+
+func TestClientTokenSourceConcurrentRefresh(t *testing.T) {
+	server := newTestRealmServer(t)
+	server.tokenDelay = 50 * time.Millisecond
+
+	source := NewClientTokenSource(gocloak.NewClient(server.URL), server.config())
+
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := source.Token(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "client_credentials-1", token.AccessToken)
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, 1, server.logins.Load())
+}
+
+func TestClientTokenSourceRefreshesBeforeExpiry(t *testing.T) {
+	server := newTestRealmServer(t)
+	source := NewClientTokenSource(gocloak.NewClient(server.URL), server.config())
+
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	_, err := source.Token(context.Background())
+	require.NoError(t, err)
+
+	// still valid, outside of the expiry delta
+	now = now.Add(200 * time.Second)
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "client_credentials-1", token.AccessToken)
+
+	// about to expire, refreshed using the refresh token
+	now = now.Add(80 * time.Second)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "refresh_token-1", token.AccessToken)
+	require.EqualValues(t, 1, server.logins.Load())
+	require.EqualValues(t, 1, server.refreshes.Load())
+}
+
+func TestClientTokenSourceShortLivedTokens(t *testing.T) {
+	server := newTestRealmServer(t)
+	server.expiresIn = 20
+	server.refreshExpiresIn = 40
+
+	source := NewClientTokenSource(gocloak.NewClient(server.URL), server.config())
+
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	_, err := source.Token(context.Background())
+	require.NoError(t, err)
+
+	// the expiry delta exceeds the lifetime, and is clamped to half of it
+	now = now.Add(5 * time.Second)
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "client_credentials-1", token.AccessToken)
+
+	now = now.Add(5 * time.Second)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "refresh_token-1", token.AccessToken)
+	require.EqualValues(t, 1, server.logins.Load())
+}
+
+func TestClientTokenSourceLoginFallback(t *testing.T) {
+	t.Run("refresh token expired", func(t *testing.T) {
+		server := newTestRealmServer(t)
+		source := NewClientTokenSource(gocloak.NewClient(server.URL), server.config())
+
+		now := time.Now()
+		source.now = func() time.Time { return now }
+
+		_, err := source.Token(context.Background())
+		require.NoError(t, err)
+
+		now = now.Add(time.Hour)
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "client_credentials-2", token.AccessToken)
+		require.EqualValues(t, 0, server.refreshes.Load())
+	})
+
+	t.Run("refresh rejected", func(t *testing.T) {
+		server := newTestRealmServer(t)
+		server.rejectRefresh = true
+		source := NewClientTokenSource(gocloak.NewClient(server.URL), server.config())
+
+		_, err := source.Token(context.Background())
+		require.NoError(t, err)
+
+		token, err := source.Refresh(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "client_credentials-2", token.AccessToken)
+		require.EqualValues(t, 1, server.refreshes.Load())
+	})
+}
+
+func TestClientTokenSourceRun(t *testing.T) {
+	server := newTestRealmServer(t)
+	server.expiresIn = 2
+
+	source := NewClientTokenSource(gocloak.NewClient(server.URL), server.config())
+	source.SetExpiryDelta(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- source.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		return server.refreshes.Load() >= 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,131 @@
+package recloak
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrTokenExpired is returned when the token `exp` claim is in the past.
+	ErrTokenExpired = jwt.ErrTokenExpired
+
+	// ErrTokenRequiredClaimMissing is returned when the token lacks a required
+	// claim, such as `exp`.
+	ErrTokenRequiredClaimMissing = jwt.ErrTokenRequiredClaimMissing
+
+	// ErrTokenNotValidYet is returned when the token `nbf` claim is in the
+	// future.
+	ErrTokenNotValidYet = jwt.ErrTokenNotValidYet
+
+	// ErrTokenUsedBeforeIssued is returned when the token `iat` claim is in
+	// the future.
+	ErrTokenUsedBeforeIssued = jwt.ErrTokenUsedBeforeIssued
+
+	// ErrInvalidIssuer is returned when the token `iss` claim does not match
+	// the expected issuer.
+	ErrInvalidIssuer = jwt.ErrTokenInvalidIssuer
+
+	// ErrInvalidAudience is returned when neither the token `aud` nor `azp`
+	// claims contain an accepted audience.
+	ErrInvalidAudience = jwt.ErrTokenInvalidAudience
+)
+
+// ValidationOptions is a struct that holds the registered claims validation
+// options applied when decoding access tokens.
+type ValidationOptions struct {
+	// The expected issuer, defaults to the realm URL.
+	Issuer string `yaml:"issuer,omitempty"`
+
+	// Whether to skip verifying the issuer.
+	SkipIssuerCheck bool `yaml:"skipIssuerCheck,omitempty"`
+
+	// Whether to skip requiring an accepted audience in the `aud` or `azp`
+	// claims. Setting it disables the audience check, so tokens minted for
+	// any client of the realm are accepted.
+	SkipAudienceCheck bool `yaml:"skipAudienceCheck,omitempty"`
+
+	// Accepted audiences, defaults to the client ID.
+	Audiences []string `yaml:"audiences,omitempty"`
+
+	// The allowed clock skew when verifying time based claims.
+	Leeway time.Duration `yaml:"leeway,omitempty"`
+}
+
+// parserOptions returns the JWT parser options for the given client config.
+func (o *ValidationOptions) parserOptions(
+	config *ClientConfig,
+) ([]jwt.ParserOption, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(o.Leeway),
+		jwt.WithIssuedAt(),
+	}
+
+	if !o.SkipIssuerCheck {
+		issuer, err := o.issuer(config)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+
+	return opts, nil
+}
+
+// authorizedPartyClaims is implemented by claims that carry the `azp` claim.
+type authorizedPartyClaims interface {
+	GetAuthorizedParty() (string, error)
+}
+
+// validateAudience checks that the claims are intended for one of the accepted
+// audiences, unless audience verification is skipped.
+func (o *ValidationOptions) validateAudience(
+	config *ClientConfig,
+	claims jwt.Claims,
+) error {
+	if o.SkipAudienceCheck {
+		return nil
+	}
+
+	audiences := o.Audiences
+	if len(audiences) == 0 {
+		audiences = []string{config.ClientID}
+	}
+
+	tokenAudiences, err := claims.GetAudience()
+	if err != nil {
+		return err
+	}
+
+	var authorizedParty string
+	if azpClaims, ok := claims.(authorizedPartyClaims); ok {
+		if authorizedParty, err = azpClaims.GetAuthorizedParty(); err != nil {
+			return err
+		}
+	}
+
+	for _, audience := range audiences {
+		if slices.Contains(tokenAudiences, audience) ||
+			authorizedParty == audience {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(
+		"%w: token is not intended for %v",
+		ErrInvalidAudience,
+		audiences,
+	)
+}
+
+func (o *ValidationOptions) issuer(config *ClientConfig) (string, error) {
+	if o.Issuer != "" {
+		return o.Issuer, nil
+	}
+
+	return url.JoinPath(config.AuthServerURL, "realms", config.Realm)
+}
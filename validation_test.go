@@ -0,0 +1,179 @@
+package recloak
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDecodeValidationOptionsFromYAML(t *testing.T) {
+	const configYAML = `
+---
+authServerUrl: https://auth.example.com
+realm: test
+clientId: client
+clientSecret: secret
+validation:
+  issuer: https://issuer.example.com/realms/test
+  skipAudienceCheck: true
+  audiences: [client, other]
+  leeway: 30s
+`
+
+	var actual ClientConfig
+	err := yaml.Unmarshal([]byte(configYAML), &actual)
+	require.NoError(t, err)
+
+	require.Equal(t, ValidationOptions{
+		Issuer:            "https://issuer.example.com/realms/test",
+		SkipAudienceCheck: true,
+		Audiences:         []string{"client", "other"},
+		Leeway:            30 * time.Second,
+	}, actual.Validation)
+}
+
+func TestDecodeAccessTokenValidation(t *testing.T) {
+	server := newTestRealmServer(t)
+	key := server.rotate(t, "first")
+
+	testData := []struct {
+		name     string
+		options  ValidationOptions
+		claims   func(*Claims)
+		expected error
+	}{
+		{
+			name:    "valid",
+			options: ValidationOptions{},
+			claims:  func(*Claims) {},
+		},
+		{
+			name:    "wrong issuer",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.Issuer = "https://evil.example.com/realms/test"
+			},
+			expected: ErrInvalidIssuer,
+		},
+		{
+			name:    "skipped issuer check",
+			options: ValidationOptions{SkipIssuerCheck: true},
+			claims: func(c *Claims) {
+				c.Issuer = "https://evil.example.com/realms/test"
+			},
+		},
+		{
+			name:    "configured issuer",
+			options: ValidationOptions{Issuer: "https://public.example.com"},
+			claims: func(c *Claims) {
+				c.Issuer = "https://public.example.com"
+			},
+		},
+		{
+			name:    "expired",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			},
+			expected: ErrTokenExpired,
+		},
+		{
+			name:    "missing expiry",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.ExpiresAt = nil
+			},
+			expected: ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:    "expired within leeway",
+			options: ValidationOptions{Leeway: 2 * time.Minute},
+			claims: func(c *Claims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			},
+		},
+		{
+			name:    "not valid yet",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+			},
+			expected: ErrTokenNotValidYet,
+		},
+		{
+			name:    "audience not verified",
+			options: ValidationOptions{SkipAudienceCheck: true},
+			claims: func(c *Claims) {
+				c.Audience = jwt.ClaimStrings{"other-client"}
+			},
+		},
+		{
+			name:    "wrong audience",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.Audience = jwt.ClaimStrings{"other-client"}
+				c.AuthorizedParty = "other-client"
+			},
+			expected: ErrInvalidAudience,
+		},
+		{
+			name:    "missing audience",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.Audience = nil
+			},
+			expected: ErrInvalidAudience,
+		},
+		{
+			name:    "audience in aud",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.Audience = jwt.ClaimStrings{"account", "client"}
+				c.AuthorizedParty = "other-client"
+			},
+		},
+		{
+			name:    "audience in azp",
+			options: ValidationOptions{},
+			claims: func(c *Claims) {
+				c.Audience = nil
+				c.AuthorizedParty = "client"
+			},
+		},
+		{
+			name:    "configured audience",
+			options: ValidationOptions{Audiences: []string{"api"}},
+			claims: func(c *Claims) {
+				c.Audience = jwt.ClaimStrings{"api"}
+			},
+		},
+	}
+
+	for _, data := range testData {
+		t.Run(data.name, func(t *testing.T) {
+			config := server.config()
+			config.Validation = data.options
+
+			client, err := NewClient(config)
+			require.NoError(t, err)
+
+			claims := server.claims()
+			data.claims(claims)
+
+			_, err = client.DecodeAccessToken(
+				context.Background(),
+				signTestToken(t, key, "first", claims),
+			)
+
+			if data.expected != nil {
+				require.ErrorIs(t, err, data.expected)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}